@@ -15,9 +15,356 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/admin/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists every API key, revoked ones included, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.APIKeyResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin key required"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues an API key for a team. The secret is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Issue API key",
+                "parameters": [
+                    {
+                        "description": "API key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.IssueAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.APIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin key required"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes an API key for good. Its links stay in place and can still be managed by admins.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin key required"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - API key has already been revoked"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/admin/keys/{id}/quota": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets the active links and daily creates a key may use. 0 uses the configured default and -1 lifts the limit. Existing links are kept when a quota is lowered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update API key quota",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quotas",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateQuotaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.APIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid quota"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin key required"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - API key has been revoked"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/admin/keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the secret of an API key, the previous secret stops working immediately. The key keeps its ID and links.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Rotate API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.APIKeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Admin key required"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - API key has been revoked"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
         "/api/shortlinks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists shorturls page by page in creation order, optionally filtered by destination host, tag and creation date range",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "List shorturls",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at or -created_at (default)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC 3339 or date)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or before (RFC 3339 or date)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid query"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
             "post": {
-                "description": "Creates a new shorturl",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new shorturl using the optional alias as its code. Without an alias, returns the live short link already assigned to the same URL unless force_new is set.",
                 "consumes": [
                     "application/json"
                 ],
@@ -37,30 +384,766 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.CreateRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key never mint a second code",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Existing short link",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateResponse"
+                        }
+                    },
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/dto.CreateResponse"
+                            "$ref": "#/definitions/dto.CreateResponse"
+                        },
+                        "headers": {
+                            "X-Quota-Daily-Creates-Remaining": {
+                                "type": "integer",
+                                "description": "Creates left for the API key today (UTC)"
+                            },
+                            "X-Quota-Links-Remaining": {
+                                "type": "integer",
+                                "description": "Active links the API key may still create"
+                            },
+                            "X-Quota-Reset": {
+                                "type": "integer",
+                                "description": "Unix time the daily creates reset"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input or alias, or a destination that is a short link which cannot be collapsed"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - Link quota of the API key or of the service used up"
+                    },
+                    "409": {
+                        "description": "Conflict - Alias already taken or a request with the same Idempotency-Key is in progress"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity - Idempotency-Key reused for a different URL, or the destination is blocked"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded or daily create quota used up, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/shortlinks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific shorturl by its ID. With path, query, user_agent, accept_language or country, preview is the destination a visit with them redirects to, such as the expansion of a template link or the destination a routing rule picks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Get shorturl by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Path of the previewed visit after the code, such as PROJ-123",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Query of the previewed visit, such as utm_source=x",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User-Agent of the previewed visit",
+                        "name": "user_agent",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Accept-Language of the previewed visit",
+                        "name": "accept_language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 3166-1 alpha-2 country of the previewed visit",
+                        "name": "country",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - id is required or missing template arguments"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired or been deleted"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Soft deletes a shorturl so it can be restored within the retention window, or removes it for good with permanent=true",
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Delete shorturl",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete permanently",
+                        "name": "permanent",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL is already deleted"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Changes the destination URL, title or tags of a shorturl. Omitted fields are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Update shorturl",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired or been deleted"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity - The destination is blocked"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/shortlinks/{id}/expiration": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Extends or shortens the lifetime of a shorturl. Expired links can be revived within the retention window.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Update shorturl expiration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "description": "New expiration",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateExpirationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid expiration"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/shortlinks/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restores a soft deleted shorturl within the retention window",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Restore shorturl",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found - Unknown or past the retention window"
+                    },
+                    "409": {
+                        "description": "Conflict - Short URL is not deleted"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/api/shortlinks/{id}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns clicks per minute, hour or day over a time range, with referrer host, browser and country breakdowns of the days the range touches. Buckets are in UTC.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Get shorturl click stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short link domain, the default domain when empty",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range start, RFC 3339 timestamp or date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Range end, RFC 3339 timestamp or date (default now)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "minute (up to 24h), hour (up to 31 days) or day (default, up to 366 days)",
+                        "name": "granularity",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.StatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid range or granularity"
+                    },
+                    "401": {
+                        "description": "Unauthorized - Missing or invalid API key"
+                    },
+                    "403": {
+                        "description": "Forbidden - The link belongs to another API key"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has been deleted"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/shortlinks/{id}": {
+            "get": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
+                    }
+                }
+            },
+            "put": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
+                    }
+                }
+            },
+            "post": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shorturl"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "short id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetShortUrlResponse"
                         }
                     },
+                    "302": {
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+                    },
                     "400": {
                         "description": "Bad Request - Invalid input"
                     },
-                    "409": {
-                        "description": "Conflict - Short URL already exists"
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
                     }
                 }
-            }
-        },
-        "/api/shortlinks/{id}": {
-            "get": {
-                "description": "Retrieves a specific shorturl by its ID",
+            },
+            "head": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
                 "consumes": [
                     "application/json"
                 ],
@@ -70,7 +1153,7 @@ const docTemplate = `{
                 "tags": [
                     "shorturl"
                 ],
-                "summary": "Get shorturl by ID",
+                "summary": "Redirect to original URL",
                 "parameters": [
                     {
                         "type": "integer",
@@ -78,6 +1161,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -87,18 +1176,40 @@ const docTemplate = `{
                             "$ref": "#/definitions/dto.GetShortUrlResponse"
                         }
                     },
+                    "302": {
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+                    },
                     "400": {
-                        "description": "id is required"
+                        "description": "Bad Request - Invalid input"
+                    },
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
                     }
                 }
-            }
-        },
-        "/shortlinks/{id}": {
-            "get": {
-                "description": "Redirects to the original URL for the given short code",
+            },
+            "patch": {
+                "description": "Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is \"/\". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.",
                 "consumes": [
                     "application/json"
                 ],
@@ -116,6 +1227,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of a protected link, browsers get a password form instead",
+                        "name": "X-Link-Password",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -126,33 +1243,165 @@ const docTemplate = `{
                         }
                     },
                     "302": {
-                        "description": "Found - Redirects to original URL"
+                        "description": "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
                     },
                     "400": {
                         "description": "Bad Request - Invalid input"
                     },
+                    "401": {
+                        "description": "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+                    },
+                    "403": {
+                        "description": "Forbidden - The destination is blocked, an HTML warning page is served"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "410": {
+                        "description": "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+                    },
+                    "429": {
+                        "description": "Too Many Requests - Rate limit exceeded, see Retry-After"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+                    },
+                    "508": {
+                        "description": "Loop Detected - The link leads through too many chained short links"
                     }
                 }
             }
         }
     },
     "definitions": {
+        "dto.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "admin": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_daily_creates": {
+                    "type": "integer"
+                },
+                "max_links": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.CreateRequest": {
             "type": "object",
             "required": [
                 "original_url"
             ],
             "properties": {
+                "alias": {
+                    "description": "Alias is an optional human-readable code used instead of a generated one",
+                    "type": "string"
+                },
+                "domain": {
+                    "description": "Domain is the branded domain serving the link, the default domain when empty",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt sets an absolute expiry, mutually exclusive with TTLSeconds",
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "description": "FallbackURL is where visits outside the activation window go, the server default when empty",
+                    "type": "string"
+                },
+                "force_new": {
+                    "description": "ForceNew mints a fresh code even if the URL already has a live one",
+                    "type": "boolean"
+                },
+                "forward_path": {
+                    "description": "ForwardPath appends the path following the code to the destination path",
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "description": "ForwardQuery merges the query of a visit into the destination query",
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is how many redirects the link serves, 1 makes a one-time link, 0 means unlimited",
+                    "type": "integer"
+                },
+                "not_after": {
+                    "type": "string"
+                },
+                "not_before": {
+                    "description": "NotBefore and NotAfter bound when the link redirects, RFC 3339 timestamps or dates,\nit is live from creation and until it expires when unset",
+                    "type": "string"
+                },
                 "original_url": {
                     "type": "string"
+                },
+                "password": {
+                    "description": "Password protects the link, visitors must give it before being redirected",
+                    "type": "string"
+                },
+                "query_conflict": {
+                    "description": "QueryConflict is incoming (default) or destination, the side keeping a parameter both queries set",
+                    "type": "string"
+                },
+                "redirect_status": {
+                    "description": "RedirectStatus is 301, 302, 307 or 308, 0 uses the server default",
+                    "type": "integer"
+                },
+                "routing_rules": {
+                    "description": "RoutingRules send matching visits elsewhere than original_url, the first matching rule wins",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RoutingRule"
+                    }
+                },
+                "tags": {
+                    "description": "Tags group links for filtering",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title is an optional human-readable label",
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "TTLSeconds sets the lifetime in seconds, 0 keeps the link forever",
+                    "type": "integer"
                 }
             }
         },
         "dto.CreateResponse": {
             "type": "object",
             "properties": {
+                "domain": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -167,25 +1416,359 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "domain": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "fallback_url": {
+                    "type": "string"
+                },
+                "forward_path": {
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is how many redirects the link serves, unlimited when 0",
+                    "type": "integer"
+                },
+                "not_after": {
+                    "type": "string"
+                },
+                "not_before": {
+                    "description": "Activation window of the link",
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "preview": {
+                    "description": "Preview is the destination of the visit described by the preview query",
+                    "type": "string"
+                },
+                "protected": {
+                    "description": "Protected tells visitors must give a password",
+                    "type": "boolean"
+                },
+                "query_conflict": {
+                    "type": "string"
+                },
+                "redirect_status": {
+                    "description": "RedirectStatus is the status redirects answer with",
+                    "type": "integer"
+                },
+                "remaining_clicks": {
+                    "description": "RemainingClicks is how many redirects a click limited link has left,\nonly reported when getting a single link",
+                    "type": "integer"
+                },
+                "routing_rules": {
+                    "description": "RoutingRules send matching visits elsewhere than original_url",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RoutingRule"
+                    }
+                },
+                "schedule_state": {
+                    "description": "ScheduleState is scheduled before not_before, ended from not_after on and active otherwise",
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "template": {
+                    "description": "Template tells the destination has placeholders filled on redirect",
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.IssueAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "admin": {
+                    "description": "Admin keys can manage keys and every link",
+                    "type": "boolean"
+                },
+                "max_daily_creates": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_links": {
+                    "description": "Quotas of the key, 0 uses the configured default and -1 lifts the limit",
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "name": {
+                    "description": "Name identifies the team or service using the key",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.GetShortUrlResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RoutingRule": {
+            "type": "object",
+            "required": [
+                "destination"
+            ],
+            "properties": {
+                "countries": {
+                    "description": "Countries lists ISO 3166-1 alpha-2 codes looked up from the client address",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "destination": {
+                    "type": "string"
+                },
+                "devices": {
+                    "description": "Devices lists device classes: desktop, mobile, tablet or bot",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "languages": {
+                    "description": "Languages lists language tags such as fr or pt-BR matched against the\npreferred language of Accept-Language, fr also matching fr-CA",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "os": {
+                    "description": "OS lists operating systems: iOS, Android, Windows, macOS, ChromeOS, Linux or Other",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "time_from": {
+                    "description": "TimeFrom and TimeTo bound a daily window as HH:MM, time_to excluded,\nwrapping past midnight when time_from is later",
+                    "type": "string"
+                },
+                "time_to": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "description": "Timezone is the IANA zone of the daily window, UTC when empty",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.StatsBucket": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "browsers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "countries": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "daily_uniques": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.VisitorsBucket"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "granularity": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "referrers": {
+                    "description": "Breakdowns cover the whole days the range touches",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.StatsBucket"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_clicks": {
+                    "type": "integer"
+                },
+                "unique_visitors": {
+                    "description": "UniqueVisitors and DailyUniques are HyperLogLog estimates over the same days",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UpdateExpirationRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "ExpiresAt sets an absolute expiry",
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "TTLSeconds sets the lifetime from now in seconds, 0 keeps the link forever",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UpdateQuotaRequest": {
+            "type": "object",
+            "properties": {
+                "max_daily_creates": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_links": {
+                    "description": "Quotas of the key, 0 uses the configured default and -1 lifts the limit",
+                    "type": "integer",
+                    "minimum": -1
+                }
+            }
+        },
+        "dto.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "fallback_url": {
+                    "description": "FallbackURL is where visits outside the activation window go, empty goes back to the server default",
+                    "type": "string"
+                },
+                "forward_path": {
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "type": "boolean"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is how many redirects the link serves in all, 0 removes the limit",
+                    "type": "integer"
+                },
+                "not_after": {
+                    "type": "string"
+                },
+                "not_before": {
+                    "description": "NotBefore and NotAfter are RFC 3339 timestamps or dates, an empty value removes the bound",
+                    "type": "string"
+                },
                 "original_url": {
                     "type": "string"
+                },
+                "password": {
+                    "description": "Password protects the link, an empty password removes the protection",
+                    "type": "string"
+                },
+                "query_conflict": {
+                    "type": "string"
+                },
+                "redirect_status": {
+                    "description": "RedirectStatus is 301, 302, 307 or 308, 0 goes back to the server default",
+                    "type": "integer"
+                },
+                "routing_rules": {
+                    "description": "RoutingRules replaces the rules of the link, an empty list removes them",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.RoutingRule"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.VisitorsBucket": {
+            "type": "object",
+            "properties": {
+                "start": {
+                    "type": "string"
+                },
+                "visitors": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
-	Host:             "",
-	BasePath:         "",
+	Version:          "1.0",
+	Host:             "localhost:8080",
+	BasePath:         "/",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Shorter API Documentation",
+	Description:      "Swagger Shorter API Documentation.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
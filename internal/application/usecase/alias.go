@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"fmt"
+	"shorter-rest-api/internal/config"
+	"strings"
+)
+
+// validateAlias checks a caller supplied alias against the configured
+// character set, length range and reserved words. The system routes are
+// always reserved, whatever the redirect prefix, so that serving
+// redirects at the root later cannot let a link shadow them.
+func (uc *shortUrlUseCase) validateAlias(alias string) error {
+	aliasCfg := uc.cfg.Alias
+	reserved := append([]string{strings.Trim(config.DefaultRedirectPrefix, "/")}, config.SystemPaths...)
+	for _, word := range append(reserved, aliasCfg.ReservedWords...) {
+		if strings.EqualFold(alias, word) {
+			return fmt.Errorf("%w: %s", ErrAliasReserved, alias)
+		}
+	}
+	length := len([]rune(alias))
+	if length < aliasCfg.MinLength || length > aliasCfg.MaxLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters", ErrInvalidAlias, aliasCfg.MinLength, aliasCfg.MaxLength)
+	}
+	for _, r := range alias {
+		if !strings.ContainsRune(aliasCfg.Charset, r) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, r)
+		}
+	}
+	return nil
+}
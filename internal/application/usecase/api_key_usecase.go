@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeySecretPrefix = "sk_"
+	// apiKeyDisplayLength is how much of a secret is kept to recognise it
+	apiKeyDisplayLength = 10
+	// bootstrapAdminID identifies the admin authenticated by the configured secret
+	bootstrapAdminID = "admin"
+)
+
+// APIKeyUseCase represents the API key use case interface
+type APIKeyUseCase interface {
+	// Authenticate returns the live key matching secret or ErrUnauthorized
+	Authenticate(ctx context.Context, secret string) (*entity.APIKey, error)
+	IssueAPIKey(ctx context.Context, request *dto.IssueAPIKeyRequest) (*dto.APIKeyResponse, error)
+	ListAPIKeys(ctx context.Context) ([]*dto.APIKeyResponse, error)
+	// RotateAPIKey replaces the secret of a key, the old secret stops working at once
+	RotateAPIKey(ctx context.Context, id string) (*dto.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	UpdateQuota(ctx context.Context, id string, request *dto.UpdateQuotaRequest) (*dto.APIKeyResponse, error)
+}
+
+type apiKeyUseCase struct {
+	apiKeyRepo repository.APIKeyRepository
+	cfg        *config.Config
+}
+
+// NewAPIKeyUseCase creates a new API key use case
+func NewAPIKeyUseCase(config *config.Config, apiKeyRepo repository.APIKeyRepository) APIKeyUseCase {
+	return &apiKeyUseCase{
+		apiKeyRepo: apiKeyRepo,
+		cfg:        config,
+	}
+}
+
+// hashAPIKey hashes a secret for storage. Secrets are random and long, so
+// a fast hash is enough to make a leaked store useless.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKeySecret generates a random secret
+func newAPIKeySecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return apiKeySecretPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func (uc *apiKeyUseCase) Authenticate(ctx context.Context, secret string) (*entity.APIKey, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil, ErrUnauthorized
+	}
+	hash := hashAPIKey(secret)
+	if uc.cfg.Auth.AdminKey != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(uc.cfg.Auth.AdminKey))) == 1 {
+		return &entity.APIKey{ID: bootstrapAdminID, Name: "bootstrap admin", Admin: true}, nil
+	}
+
+	key, err := uc.apiKeyRepo.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	if key.IsRevoked() {
+		return nil, ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (uc *apiKeyUseCase) IssueAPIKey(ctx context.Context, request *dto.IssueAPIKeyRequest) (*dto.APIKeyResponse, error) {
+	if err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateQuota(request.MaxLinks, request.MaxDailyCreates); err != nil {
+		return nil, err
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key := &entity.APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      strings.TrimSpace(request.Name),
+		Hash:      hashAPIKey(secret),
+		Prefix:    secret[:apiKeyDisplayLength],
+		Admin:     request.Admin,
+		CreatedAt: time.Now(),
+
+		MaxLinks:        request.MaxLinks,
+		MaxDailyCreates: request.MaxDailyCreates,
+	}
+	if err := uc.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	response := toAPIKeyResponse(key)
+	response.Secret = secret
+	return response, nil
+}
+
+func (uc *apiKeyUseCase) ListAPIKeys(ctx context.Context) ([]*dto.APIKeyResponse, error) {
+	if err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	keys, err := uc.apiKeyRepo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	response := make([]*dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toAPIKeyResponse(key))
+	}
+	return response, nil
+}
+
+func (uc *apiKeyUseCase) RotateAPIKey(ctx context.Context, id string) (*dto.APIKeyResponse, error) {
+	key, err := uc.getLiveKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key.Hash = hashAPIKey(secret)
+	key.Prefix = secret[:apiKeyDisplayLength]
+	key.RotatedAt = &now
+	if err := uc.apiKeyRepo.UpdateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+	response := toAPIKeyResponse(key)
+	response.Secret = secret
+	return response, nil
+}
+
+// RevokeAPIKey disables a key for good. The links it owns stay in place
+// and remain manageable by admins.
+func (uc *apiKeyUseCase) RevokeAPIKey(ctx context.Context, id string) error {
+	key, err := uc.getLiveKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := uc.apiKeyRepo.UpdateAPIKey(ctx, key); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// UpdateQuota changes the quotas of a key. Lowering a quota below the
+// current usage only blocks new links, existing ones are kept.
+func (uc *apiKeyUseCase) UpdateQuota(ctx context.Context, id string, request *dto.UpdateQuotaRequest) (*dto.APIKeyResponse, error) {
+	if err := validateQuota(request.MaxLinks, request.MaxDailyCreates); err != nil {
+		return nil, err
+	}
+	key, err := uc.getLiveKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key.MaxLinks = request.MaxLinks
+	key.MaxDailyCreates = request.MaxDailyCreates
+	if err := uc.apiKeyRepo.UpdateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to update api key quota: %w", err)
+	}
+	return toAPIKeyResponse(key), nil
+}
+
+// validateQuota accepts 0 for the configured default, -1 for unlimited
+// and positive limits
+func validateQuota(limits ...int) error {
+	for _, limit := range limits {
+		if limit < -1 {
+			return fmt.Errorf("%w: %d is below -1", ErrInvalidQuota, limit)
+		}
+	}
+	return nil
+}
+
+// getLiveKey loads a key that is not revoked on behalf of an admin
+func (uc *apiKeyUseCase) getLiveKey(ctx context.Context, id string) (*entity.APIKey, error) {
+	if err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	key, err := uc.apiKeyRepo.GetAPIKey(ctx, id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	if key.IsRevoked() {
+		return nil, ErrAPIKeyRevoked
+	}
+	return key, nil
+}
+
+// toAPIKeyResponse maps an API key to its response DTO, without the secret
+func toAPIKeyResponse(key *entity.APIKey) *dto.APIKeyResponse {
+	response := &dto.APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Admin:     key.Admin,
+		CreatedAt: key.CreatedAt.Format(timeLayout),
+
+		MaxLinks:        key.MaxLinks,
+		MaxDailyCreates: key.MaxDailyCreates,
+	}
+	if key.RotatedAt != nil {
+		response.RotatedAt = key.RotatedAt.Format(timeLayout)
+	}
+	if key.RevokedAt != nil {
+		response.RevokedAt = key.RevokedAt.Format(timeLayout)
+	}
+	return response
+}
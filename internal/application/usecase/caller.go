@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the API key making the request
+func WithCaller(ctx context.Context, key *entity.APIKey) context.Context {
+	return context.WithValue(ctx, callerKey{}, key)
+}
+
+// CallerFrom returns the API key making the request, nil when the request
+// is not authenticated because authentication is disabled
+func CallerFrom(ctx context.Context) *entity.APIKey {
+	key, _ := ctx.Value(callerKey{}).(*entity.APIKey)
+	return key
+}
+
+// callerID returns the ID of the calling key, empty when anonymous
+func callerID(ctx context.Context) string {
+	if caller := CallerFrom(ctx); caller != nil {
+		return caller.ID
+	}
+	return ""
+}
+
+// authorizeLink allows anonymous callers, admins and the owner of the link
+func authorizeLink(ctx context.Context, link *entity.ShortURL) error {
+	caller := CallerFrom(ctx)
+	if caller == nil || caller.Admin || caller.ID == link.OwnerID {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// authorizeAdmin allows anonymous callers and admins
+func authorizeAdmin(ctx context.Context) error {
+	if caller := CallerFrom(ctx); caller != nil && !caller.Admin {
+		return ErrForbidden
+	}
+	return nil
+}
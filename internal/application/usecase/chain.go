@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"slices"
+	"strings"
+)
+
+// defaultMaxChainDepth is used when no maximum chain depth is configured
+const defaultMaxChainDepth = 3
+
+// shortLinkRef addresses the link a destination on one of our own short
+// link domains points at
+type shortLinkRef struct {
+	domain string
+	code   string
+	visit  dto.RedirectRequest
+}
+
+// linkOf returns the link destination points at when it is a redirect URL
+// of one of our domains. Redirects are served under the configured prefix
+// and the default one, but never under the system paths.
+func (d publicDomains) linkOf(destination string) (shortLinkRef, bool) {
+	parsed, err := url.Parse(destination)
+	if err != nil || parsed.Host == "" {
+		return shortLinkRef{}, false
+	}
+	host := hostName(parsed.Host)
+	domain := ""
+	if host != hostName(d.baseURL) {
+		if _, ok := d.branded[host]; !ok {
+			return shortLinkRef{}, false
+		}
+		domain = host
+	}
+	for _, prefix := range []string{d.path, config.DefaultRedirectPrefix + "/"} {
+		rest, ok := strings.CutPrefix(parsed.EscapedPath(), prefix)
+		if !ok {
+			continue
+		}
+		code, path, _ := strings.Cut(rest, "/")
+		if code, err = url.PathUnescape(code); err != nil || code == "" || slices.Contains(config.SystemPaths, code) {
+			continue
+		}
+		if path != "" {
+			if path, err = url.PathUnescape(path); err != nil {
+				continue
+			}
+			path = "/" + path
+		}
+		return shortLinkRef{domain: domain, code: code, visit: dto.RedirectRequest{Path: path, Query: parsed.RawQuery}}, true
+	}
+	return shortLinkRef{}, false
+}
+
+// followShortLinks resolves a destination pointing at our own short links
+// to where the chain of links ends or reaches a password protected, click
+// limited, scheduled or routed link, failing as a visit of a link in the
+// chain would. A chain longer than the maximum depth, which counts the link being
+// visited or created, is a loop or close enough to one to fail with
+// ErrRedirectLoop.
+func (uc *shortUrlUseCase) followShortLinks(ctx context.Context, location string) (string, error) {
+	maxDepth := uc.cfg.URL.MaxChainDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxChainDepth
+	}
+	for depth := 1; ; depth++ {
+		ref, ok := uc.domains.linkOf(location)
+		if !ok {
+			return location, nil
+		}
+		if depth >= maxDepth {
+			return "", fmt.Errorf("%w: more than %d short links chained", ErrRedirectLoop, maxDepth)
+		}
+		link, err := uc.getActiveLink(WithDomain(ctx, ref.domain), ref.code)
+		if err != nil {
+			return "", err
+		}
+		// Protected links ask for their password themselves, limited links
+		// count their clicks, scheduled links check their window and routed
+		// links route the visitor
+		if link.PasswordHash != "" || link.MaxClicks > 0 || link.HasSchedule() || len(link.RoutingRules) > 0 {
+			return location, nil
+		}
+		if location, err = destination(link, link.OriginalURL, &ref.visit); err != nil {
+			return "", err
+		}
+	}
+}
+
+// collapseShortLinks replaces a destination pointing at our own short
+// links by the destination they end at, so that no chains are stored, or
+// refuses it when short links are not accepted as destinations
+func (uc *shortUrlUseCase) collapseShortLinks(ctx context.Context, destination string) (string, error) {
+	if _, ok := uc.domains.linkOf(destination); !ok {
+		return destination, nil
+	}
+	if uc.cfg.URL.RejectShortLinks {
+		return "", fmt.Errorf("%w: %s", ErrShortLinkDestination, destination)
+	}
+	// Whatever stops the chain, such as a deleted link, is a problem of
+	// the destination and not of the link being written
+	final, err := uc.followShortLinks(ctx, destination)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrShortLinkDestination, destination, err)
+	}
+	return final, nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/entity"
+)
+
+// Click limited links serve MaxClicks redirects and answer 410 Gone after.
+// The storage layer counts the redirects atomically, so concurrent visits
+// never get past the limit. Previews, HEAD requests and failed visits are
+// not counted.
+
+// ExhaustedLinkError is returned when a click limited link served all its
+// redirects, its message is the one configured for visitors
+type ExhaustedLinkError struct {
+	Message string
+}
+
+func (e *ExhaustedLinkError) Error() string {
+	return e.Message
+}
+
+func (e *ExhaustedLinkError) Unwrap() error {
+	return ErrLinkExhausted
+}
+
+// validateMaxClicks checks the click limit requested for a link, 0
+// standing for unlimited
+func validateMaxClicks(maxClicks int) error {
+	if maxClicks < 0 {
+		return fmt.Errorf("%w: %d must not be negative", ErrInvalidMaxClicks, maxClicks)
+	}
+	return nil
+}
+
+// consumeClick counts a redirect of link against its click limit,
+// returning an ExhaustedLinkError once the limit is reached
+func (uc *shortUrlUseCase) consumeClick(ctx context.Context, link *entity.ShortURL) error {
+	if link.MaxClicks == 0 {
+		return nil
+	}
+	_, counted, err := uc.linkRepo.ConsumeClick(ctx, link.Key(), link.MaxClicks)
+	if err != nil {
+		return fmt.Errorf("failed to count click: %w", err)
+	}
+	if !counted {
+		return uc.exhausted()
+	}
+	return nil
+}
+
+// checkClicksLeft returns an ExhaustedLinkError when link has no redirects
+// left, without using one up
+func (uc *shortUrlUseCase) checkClicksLeft(ctx context.Context, link *entity.ShortURL) error {
+	if link.MaxClicks == 0 {
+		return nil
+	}
+	remaining, err := uc.remainingClicks(ctx, link)
+	if err != nil {
+		return err
+	}
+	if remaining == 0 {
+		return uc.exhausted()
+	}
+	return nil
+}
+
+// exhausted returns the error of a link that served all its redirects
+func (uc *shortUrlUseCase) exhausted() error {
+	message := uc.cfg.ExhaustedLinkMessage
+	if message == "" {
+		message = config.DefaultExhaustedLinkMessage
+	}
+	return &ExhaustedLinkError{Message: message}
+}
+
+// remainingClicks returns how many redirects a click limited link has left
+func (uc *shortUrlUseCase) remainingClicks(ctx context.Context, link *entity.ShortURL) (int, error) {
+	consumed, err := uc.linkRepo.ConsumedClicks(ctx, link.Key())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consumed clicks: %w", err)
+	}
+	return max(link.MaxClicks-consumed, 0), nil
+}
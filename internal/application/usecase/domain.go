@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/entity"
+	"strings"
+)
+
+type domainKey struct{}
+
+// WithDomain returns a context addressing the links of a branded domain,
+// as returned by ResolveDomain. An empty domain addresses the default domain.
+func WithDomain(ctx context.Context, domain string) context.Context {
+	return context.WithValue(ctx, domainKey{}, domain)
+}
+
+// DomainFrom returns the domain addressed by the request, empty for the default domain
+func DomainFrom(ctx context.Context) string {
+	domain, _ := ctx.Value(domainKey{}).(string)
+	return domain
+}
+
+// linkKey returns the storage key of code on the domain addressed by the request
+func linkKey(ctx context.Context, code string) string {
+	return entity.LinkKey(DomainFrom(ctx), code)
+}
+
+// publicDomains knows the public URLs of the default and branded domains
+type publicDomains struct {
+	baseURL string            // Scheme and host of the default domain
+	branded map[string]string // Scheme and host by lowercase host name
+	path    string            // Route prefix of the redirects, with a trailing slash
+}
+
+func newPublicDomains(cfg *config.Config) publicDomains {
+	domains := publicDomains{
+		baseURL: cfg.Server.PublicBaseURL,
+		branded: make(map[string]string, len(cfg.Server.ShortDomains)),
+		path:    cfg.Server.RedirectPrefix + "/",
+	}
+	if domains.baseURL == "" {
+		domains.baseURL = "http://localhost:" + cfg.Server.Port
+	}
+	for _, domainURL := range cfg.Server.ShortDomains {
+		domains.branded[hostName(domainURL)] = domainURL
+	}
+	return domains
+}
+
+// hostName returns the lowercase host name of a URL or Host header, without port
+func hostName(value string) string {
+	if _, rest, ok := strings.Cut(value, "://"); ok {
+		value = rest
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return strings.ToLower(strings.TrimSuffix(value, "."))
+}
+
+// resolve returns the domain links are stored with for a requested domain,
+// empty for the default domain
+func (d publicDomains) resolve(domain string) (string, error) {
+	host := hostName(strings.TrimSpace(domain))
+	if host == "" || host == hostName(d.baseURL) {
+		return "", nil
+	}
+	if _, ok := d.branded[host]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownDomain, domain)
+	}
+	return host, nil
+}
+
+// forHost returns the domain serving requests to host, empty for the
+// default domain, which also serves every unknown host
+func (d publicDomains) forHost(host string) string {
+	host = hostName(host)
+	if _, ok := d.branded[host]; ok {
+		return host
+	}
+	return ""
+}
+
+// shortURL returns the public URL of a link
+func (d publicDomains) shortURL(link *entity.ShortURL) string {
+	baseURL := d.baseURL
+	if domainURL, ok := d.branded[link.Domain]; ok {
+		baseURL = domainURL
+	}
+	return baseURL + d.path + link.Code
+}
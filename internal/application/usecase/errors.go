@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"errors"
+	"shorter-rest-api/internal/domain/repository"
+)
+
+var (
+	// ErrLinkNotFound is returned when no short URL exists for a code
+	ErrLinkNotFound = repository.ErrLinkNotFound
+	// ErrLinkExpired is returned when a short URL outlived its expiry
+	ErrLinkExpired = errors.New("short url has expired")
+	// ErrLinkDeleted is returned when a short URL is soft deleted
+	ErrLinkDeleted = errors.New("short url has been deleted")
+	// ErrLinkNotDeleted is returned when restoring a short URL that is not deleted
+	ErrLinkNotDeleted = errors.New("short url is not deleted")
+	// ErrInvalidOriginalURL is returned when the destination URL is not usable
+	ErrInvalidOriginalURL = errors.New("invalid original url")
+	// ErrInvalidListQuery is returned when a listing query cannot be used
+	ErrInvalidListQuery = errors.New("invalid list query")
+	// ErrInvalidStatsQuery is returned when a stats range or granularity cannot be used
+	ErrInvalidStatsQuery = errors.New("invalid stats query")
+	// ErrInvalidExpiration is returned when the requested expiry is not usable
+	ErrInvalidExpiration = errors.New("invalid expiration")
+	// ErrIdempotencyKeyInProgress is returned while the first request with the same key is still running
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrIdempotencyKeyReused is returned when a key is replayed with a different original URL
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different original url")
+	// ErrInvalidAlias is returned when an alias breaks the configured charset or length rules
+	ErrInvalidAlias = errors.New("invalid alias")
+	// ErrAliasReserved is returned when an alias matches a reserved word
+	ErrAliasReserved = errors.New("alias is reserved")
+	// ErrAliasTaken is returned when an alias is already used by another link
+	ErrAliasTaken = errors.New("alias is already taken")
+	// ErrUnauthorized is returned when a request carries no valid API key
+	ErrUnauthorized = errors.New("missing or invalid api key")
+	// ErrForbidden is returned when the API key may not act on the resource
+	ErrForbidden = errors.New("api key is not allowed to access this resource")
+	// ErrAPIKeyNotFound is returned when no API key exists for an ID
+	ErrAPIKeyNotFound = repository.ErrAPIKeyNotFound
+	// ErrAPIKeyRevoked is returned when rotating or revoking a revoked key
+	ErrAPIKeyRevoked = errors.New("api key has been revoked")
+	// ErrQuotaExceeded is matched by QuotaExceededError when a quota is used up
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrUnknownDomain is returned when a domain is not configured for short links
+	ErrUnknownDomain = errors.New("unknown short link domain")
+	// ErrInvalidQuota is returned when a quota limit cannot be used
+	ErrInvalidQuota = errors.New("invalid quota")
+	// ErrInvalidRedirectStatus is returned when a link asks for a status that is not a redirect
+	ErrInvalidRedirectStatus = errors.New("invalid redirect status")
+	// ErrInvalidQueryConflict is returned when a query conflict rule is neither incoming nor destination
+	ErrInvalidQueryConflict = errors.New("invalid query conflict rule")
+	// ErrInvalidTemplate is returned when the placeholders of a template link cannot be used
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrMissingTemplateArgs is returned when a visit of a template link lacks path segments
+	ErrMissingTemplateArgs = errors.New("missing template arguments")
+	// ErrDestinationBlocked is matched by BlockedDestinationError when the destination policy blocks a URL
+	ErrDestinationBlocked = errors.New("destination is blocked")
+	// ErrShortLinkDestination is returned when a destination is a short link that cannot be collapsed
+	ErrShortLinkDestination = errors.New("destination is a short link")
+	// ErrRedirectLoop is returned when a visit would pass through more chained short links than allowed
+	ErrRedirectLoop = errors.New("too many chained short links")
+	// ErrInvalidPassword is returned when the password of a link is too short or too long
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrPasswordRequired is returned when a visit of a password protected link carries no password
+	ErrPasswordRequired = errors.New("password required")
+	// ErrWrongPassword is returned when a visit of a password protected link carries a wrong password
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrInvalidMaxClicks is returned when the click limit of a link is negative
+	ErrInvalidMaxClicks = errors.New("invalid max clicks")
+	// ErrLinkExhausted is matched by ExhaustedLinkError when a click limited link served all its redirects
+	ErrLinkExhausted = errors.New("short url has reached its click limit")
+	// ErrInvalidSchedule is returned when the activation window of a link cannot be used
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrLinkNotYetActive is matched by ScheduledLinkError when a link is visited before its activation window
+	ErrLinkNotYetActive = errors.New("short url is not active yet")
+	// ErrLinkEnded is returned when a link is visited after its activation window
+	ErrLinkEnded = errors.New("short url is no longer active")
+	// ErrInvalidRoutingRule is returned when a routing rule of a link cannot be used
+	ErrInvalidRoutingRule = errors.New("invalid routing rule")
+)
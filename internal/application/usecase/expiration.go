@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/domain/dto"
+	"time"
+)
+
+// resolveExpiry turns the requested absolute or relative expiry into the
+// link's expiry time. A nil result means the link never expires.
+func (uc *shortUrlUseCase) resolveExpiry(expiresAt *time.Time, ttlSeconds *int64, now time.Time) (*time.Time, bool, error) {
+	if expiresAt != nil && ttlSeconds != nil {
+		return nil, false, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", ErrInvalidExpiration)
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, false, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+		}
+		return expiresAt, true, nil
+	}
+	if ttlSeconds != nil {
+		if *ttlSeconds < 0 {
+			return nil, false, fmt.Errorf("%w: ttl_seconds must not be negative", ErrInvalidExpiration)
+		}
+		if *ttlSeconds == 0 {
+			return nil, true, nil
+		}
+		expiry := now.Add(time.Duration(*ttlSeconds) * time.Second)
+		return &expiry, true, nil
+	}
+	return nil, false, nil
+}
+
+// defaultExpiry applies the configured default lifetime, 0 meaning never
+func (uc *shortUrlUseCase) defaultExpiry(now time.Time) *time.Time {
+	if uc.cfg.Expiration <= 0 {
+		return nil
+	}
+	expiry := now.Add(time.Duration(uc.cfg.Expiration) * time.Second)
+	return &expiry
+}
+
+// storageTTL keeps expired links around for the retention window so
+// they can still be told apart from links that never existed
+func (uc *shortUrlUseCase) storageTTL(expiresAt *time.Time, now time.Time) time.Duration {
+	if expiresAt == nil {
+		return 0
+	}
+	ttl := expiresAt.Sub(now) + time.Duration(uc.cfg.ExpiredLinkRetention)*time.Second
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return ttl
+}
+
+// UpdateExpiration extends or shortens the lifetime of an existing link.
+// Links that already expired can be revived within the retention window.
+func (uc *shortUrlUseCase) UpdateExpiration(ctx context.Context, code string, request *dto.UpdateExpirationRequest) (*dto.GetShortUrlResponse, error) {
+	now := time.Now()
+	expiresAt, ok, err := uc.resolveExpiry(request.ExpiresAt, request.TTLSeconds, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: expires_at or ttl_seconds is required", ErrInvalidExpiration)
+	}
+
+	shortUrl, err := uc.getLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return nil, err
+	}
+	// Reviving an expired link makes it count against the link quotas again
+	if shortUrl.IsExpired(now) && !shortUrl.IsDeleted() && (expiresAt == nil || expiresAt.After(now)) {
+		if err := uc.checkReactivation(ctx); err != nil {
+			return nil, err
+		}
+	}
+	shortUrl.ExpiresAt = expiresAt
+	if err := uc.linkRepo.Update(ctx, shortUrl, uc.storageTTL(expiresAt, now)); err != nil {
+		return nil, fmt.Errorf("failed to update short url: %w", err)
+	}
+	return uc.toGetResponse(shortUrl), nil
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// ListShortUrls returns a page of links in creation order, continuing
+// after the opaque cursor returned with the previous page. Callers other
+// than admins only see their own links.
+func (uc *shortUrlUseCase) ListShortUrls(ctx context.Context, request *dto.ListRequest) (*dto.ListResponse, error) {
+	filter, err := toLinkFilter(request)
+	if err != nil {
+		return nil, err
+	}
+	if request.Domain != "" {
+		domain, err := uc.domains.resolve(request.Domain)
+		if err != nil {
+			return nil, err
+		}
+		filter.Domain = &domain
+	}
+	if caller := CallerFrom(ctx); caller != nil && !caller.Admin {
+		filter.OwnerID = caller.ID
+	}
+
+	// Ask for one extra link to know whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	links, err := uc.linkRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list short urls: %w", err)
+	}
+
+	response := &dto.ListResponse{Items: make([]*dto.GetShortUrlResponse, 0, len(links))}
+	if len(links) > limit {
+		links = links[:limit]
+		response.NextCursor = encodeCursor(repository.CursorOf(links[limit-1]))
+	}
+	for _, link := range links {
+		response.Items = append(response.Items, uc.toGetResponse(link))
+	}
+	return response, nil
+}
+
+func toLinkFilter(request *dto.ListRequest) (repository.LinkFilter, error) {
+	filter := repository.LinkFilter{
+		Host:  strings.ToLower(strings.TrimSpace(request.Host)),
+		Tag:   strings.ToLower(strings.TrimSpace(request.Tag)),
+		Limit: request.Limit,
+	}
+
+	switch request.Sort {
+	case "", "-created_at":
+		filter.Descending = true
+	case "created_at":
+	default:
+		return filter, fmt.Errorf("%w: sort must be created_at or -created_at", ErrInvalidListQuery)
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit < 0 || filter.Limit > maxListLimit {
+		return filter, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, maxListLimit)
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseQueryTime(request.CreatedFrom, "created_from", false, ErrInvalidListQuery); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseQueryTime(request.CreatedTo, "created_to", true, ErrInvalidListQuery); err != nil {
+		return filter, err
+	}
+	if request.Cursor != "" {
+		cursor, err := decodeCursor(request.Cursor)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = &cursor
+	}
+	return filter, nil
+}
+
+// parseQueryTime accepts RFC 3339 timestamps or plain dates, which cover
+// the whole day when used as an upper bound. Malformed values are reported
+// wrapped in invalid.
+func parseQueryTime(value, name string, upperBound bool, invalid error) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		if upperBound {
+			parsed = parsed.Add(24*time.Hour - time.Millisecond)
+		}
+		return &parsed, nil
+	}
+	return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp or a date", invalid, name)
+}
+
+func encodeCursor(cursor repository.LinkCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt, 10) + ":" + cursor.Code
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (repository.LinkCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return repository.LinkCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	createdAt, code, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return repository.LinkCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	millis, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return repository.LinkCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+	}
+	return repository.LinkCursor{CreatedAt: millis, Code: code}, nil
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"shorter-rest-api/internal/infrastructure/utils"
+	"slices"
+	"strings"
+)
+
+// defaultAllowedSchemes are the destination schemes when none are configured
+var defaultAllowedSchemes = []string{"http", "https"}
+
+// normalizeOriginalURL validates a destination and returns its canonical
+// form, which the duplicate index is keyed by. Destinations must use an
+// allowed scheme, so javascript: or data: URLs never become redirect
+// targets. The placeholders of templates are not valid URL characters and
+// would be escaped, so only the scheme and host of templates are normalized.
+// Destinations pointing at our own short links are collapsed to where they
+// lead, see collapseShortLinks.
+func (uc *shortUrlUseCase) normalizeOriginalURL(ctx context.Context, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: original_url must not be empty", ErrInvalidOriginalURL)
+	}
+
+	var canonical string
+	var err error
+	if !strings.Contains(raw, "{") {
+		canonical, err = utils.NormalizeURL(raw, uc.cfg.URL.SortQuery)
+	} else if scheme, authority, rest, ok := splitAuthority(raw); ok {
+		// Visitors of templates must not choose the host they land on
+		if strings.Contains(authority, "{") {
+			return "", fmt.Errorf("%w: placeholders must follow the host", ErrInvalidTemplate)
+		}
+		canonical, err = utils.NormalizeURL(scheme+"://"+authority, false)
+		canonical += rest
+	} else {
+		err = fmt.Errorf("not an absolute URL")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidOriginalURL, err)
+	}
+	if isTemplate(canonical) {
+		if _, ok := uc.domains.linkOf(templatePlaceholder.ReplaceAllString(canonical, "x")); ok {
+			return "", fmt.Errorf("%w: templates must not point at short links", ErrShortLinkDestination)
+		}
+	} else if collapsed, err := uc.collapseShortLinks(ctx, canonical); err != nil {
+		return "", err
+	} else if collapsed != canonical {
+		// Passed through paths and queries may need normalizing too
+		if canonical, err = utils.NormalizeURL(collapsed, uc.cfg.URL.SortQuery); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidOriginalURL, err)
+		}
+	}
+	if err := uc.checkDestination(templatePlaceholder.ReplaceAllString(canonical, "x")); err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
+
+// checkDestination refuses destinations with a scheme that is not allowed,
+// without a host, with credentials that could disguise the real host, or
+// blocked by the destination policy
+func (uc *shortUrlUseCase) checkDestination(destination string) error {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOriginalURL, err)
+	}
+	allowed := uc.cfg.URL.AllowedSchemes
+	if len(allowed) == 0 {
+		allowed = defaultAllowedSchemes
+	}
+	if !slices.Contains(allowed, parsed.Scheme) {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidOriginalURL, parsed.Scheme)
+	}
+	if parsed.Host == "" && (parsed.Opaque == "" || utils.NeedsHost(parsed.Scheme)) {
+		return fmt.Errorf("%w: %s URLs need a host", ErrInvalidOriginalURL, parsed.Scheme)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: credentials are not allowed", ErrInvalidOriginalURL)
+	}
+	return uc.checkPolicy(destination)
+}
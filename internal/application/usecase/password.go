@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password protected links ask for their password before redirecting. A
+// visit with the right password gets an access token, which browsers keep
+// in a cookie, letting later visits through until it expires.
+const (
+	minPasswordLength = 4
+	// maxPasswordLength is where bcrypt stops reading
+	maxPasswordLength = 72
+	// defaultAccessTTL is used when no access token lifetime is configured
+	defaultAccessTTL = time.Hour
+)
+
+// hashPassword validates the password of a link and returns its bcrypt hash
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("%w: must be %d to %d bytes long", ErrInvalidPassword, minPasswordLength, maxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// newAccessSecret returns the configured secret signing access tokens, or
+// a random one that lasts as long as the process
+func newAccessSecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	random := make([]byte, 32)
+	rand.Read(random)
+	return random
+}
+
+// unlock lets a visit of link through when the link has no password, when
+// the visit carries its password or a valid access token. An access token
+// is returned when the password was just accepted.
+func (uc *shortUrlUseCase) unlock(link *entity.ShortURL, request *dto.RedirectRequest, now time.Time) (string, time.Time, error) {
+	if link.PasswordHash == "" {
+		return "", time.Time{}, nil
+	}
+	if request.Password == "" {
+		if uc.validAccessToken(link, request.AccessToken, now) {
+			return "", time.Time{}, nil
+		}
+		return "", time.Time{}, ErrPasswordRequired
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(request.Password)) != nil {
+		return "", time.Time{}, ErrWrongPassword
+	}
+	ttl := time.Duration(uc.cfg.Password.AccessTTL) * time.Second
+	if ttl <= 0 {
+		ttl = defaultAccessTTL
+	}
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	return uc.accessToken(link, expiresAt), expiresAt, nil
+}
+
+// accessToken signs the key and password hash of link with the expiry of
+// the token, so that changing the password revokes the tokens handed out
+func (uc *shortUrlUseCase) accessToken(link *entity.ShortURL, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	mac := hmac.New(sha256.New, uc.accessSecret)
+	mac.Write([]byte(link.Key() + "\n" + link.PasswordHash + "\n" + expiry))
+	return expiry + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validAccessToken reports whether token unlocks link at now
+func (uc *shortUrlUseCase) validAccessToken(link *entity.ShortURL, token string, now time.Time) bool {
+	expiry, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !now.Before(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(uc.accessToken(link, time.Unix(unix, 0))))
+}
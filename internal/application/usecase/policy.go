@@ -0,0 +1,37 @@
+package usecase
+
+import "fmt"
+
+// DestinationPolicy vets the destinations of links
+type DestinationPolicy interface {
+	// Check returns why destination is blocked, empty when it passes
+	Check(destination string) string
+}
+
+// BlockedDestinationError is returned when the destination policy blocks a
+// destination, when shortening it or when a visit would land on it
+type BlockedDestinationError struct {
+	Destination string
+	Reason      string
+}
+
+func (e *BlockedDestinationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDestinationBlocked, e.Reason)
+}
+
+func (e *BlockedDestinationError) Unwrap() error {
+	return ErrDestinationBlocked
+}
+
+// checkPolicy returns a BlockedDestinationError when the policy blocks
+// destination. Links created before a destination was blocked are checked
+// again on every visit.
+func (uc *shortUrlUseCase) checkPolicy(destination string) error {
+	if uc.policy == nil {
+		return nil
+	}
+	if reason := uc.policy.Check(destination); reason != "" {
+		return &BlockedDestinationError{Destination: destination, Reason: reason}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+)
+
+const (
+	// QuotaLinks limits the active links an API key owns
+	QuotaLinks = "links"
+	// QuotaDailyCreates limits the links an API key creates per UTC day
+	QuotaDailyCreates = "daily_creates"
+	// QuotaGlobalLinks limits the active links of the whole service
+	QuotaGlobalLinks = "global_links"
+)
+
+// QuotaExceededError is returned when a create would go over a quota.
+// It matches ErrQuotaExceeded with errors.Is.
+type QuotaExceededError struct {
+	Quota   string
+	Limit   int
+	ResetAt *time.Time // when the quota frees up again, nil unless it resets on its own
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %d reached", ErrQuotaExceeded, e.Quota, e.Limit)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// quotaLimit resolves a limit of a key, 0 falls back to the configured
+// default and a negative value lifts the limit. 0 is returned when unlimited.
+func quotaLimit(keyLimit, defaultLimit int) int {
+	if keyLimit == 0 {
+		keyLimit = defaultLimit
+	}
+	if keyLimit < 0 {
+		return 0
+	}
+	return keyLimit
+}
+
+// nextQuotaDay returns the start of the UTC day after now, when daily quotas reset
+func nextQuotaDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// reserveCreate checks the quotas of the caller before a link is created
+// and counts the create against its daily quota. Admins only count
+// against the global cap. The returned status must be released when the
+// create fails.
+//
+// The active link counts are only checked here to answer early, the
+// repository enforces the linkLimits atomically with the create itself.
+func (uc *shortUrlUseCase) reserveCreate(ctx context.Context, now time.Time) (*dto.QuotaStatus, error) {
+	if err := uc.checkGlobalLinks(ctx); err != nil {
+		return nil, err
+	}
+	caller := CallerFrom(ctx)
+	if caller == nil || caller.Admin {
+		return nil, nil
+	}
+
+	status, err := uc.checkOwnerLinks(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+	dailyLimit := quotaLimit(caller.MaxDailyCreates, uc.cfg.Quota.MaxDailyCreates)
+	if dailyLimit == 0 {
+		return status, nil
+	}
+	resetAt := nextQuotaDay(now)
+	used, reserved, err := uc.quotaRepo.ReserveDailyCreate(ctx, caller.ID, now, dailyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve daily create: %w", err)
+	}
+	if !reserved {
+		return nil, &QuotaExceededError{Quota: QuotaDailyCreates, Limit: dailyLimit, ResetAt: &resetAt}
+	}
+	if status == nil {
+		status = &dto.QuotaStatus{}
+	}
+	status.DailyCreatesLimit = dailyLimit
+	status.DailyCreatesRemaining = dailyLimit - used
+	status.ResetAt = resetAt.Unix()
+	return status, nil
+}
+
+// linkLimits returns the active link limits a create of the caller must
+// stay within
+func (uc *shortUrlUseCase) linkLimits(ctx context.Context) repository.LinkLimits {
+	limits := repository.LinkLimits{Total: max(uc.cfg.MaximumShortUrlCount, 0)}
+	if caller := CallerFrom(ctx); caller != nil && !caller.Admin {
+		limits.Owner = quotaLimit(caller.MaxLinks, uc.cfg.Quota.MaxLinks)
+	}
+	return limits
+}
+
+// linkLimitError maps a link limit the repository refused a create over
+// to the quota it enforces, nil for other errors
+func linkLimitError(err error, limits repository.LinkLimits) error {
+	switch {
+	case errors.Is(err, repository.ErrTotalLinkLimit):
+		return &QuotaExceededError{Quota: QuotaGlobalLinks, Limit: limits.Total}
+	case errors.Is(err, repository.ErrOwnerLinkLimit):
+		return &QuotaExceededError{Quota: QuotaLinks, Limit: limits.Owner}
+	}
+	return nil
+}
+
+// releaseCreate gives back the daily create reserved for a create that failed
+func (uc *shortUrlUseCase) releaseCreate(ctx context.Context, status *dto.QuotaStatus, now time.Time) {
+	if status == nil || status.DailyCreatesLimit == 0 {
+		return
+	}
+	if err := uc.quotaRepo.ReleaseDailyCreate(ctx, callerID(ctx), now); err != nil {
+		log.Printf("Failed to release daily create: %v", err)
+	}
+}
+
+// checkReactivation checks the link quotas before an inactive link becomes
+// active again, so deleting and restoring links cannot bypass them
+func (uc *shortUrlUseCase) checkReactivation(ctx context.Context) error {
+	if err := uc.checkGlobalLinks(ctx); err != nil {
+		return err
+	}
+	if caller := CallerFrom(ctx); caller != nil && !caller.Admin {
+		_, err := uc.checkOwnerLinks(ctx, caller)
+		return err
+	}
+	return nil
+}
+
+// checkGlobalLinks enforces the service wide cap on active links
+func (uc *shortUrlUseCase) checkGlobalLinks(ctx context.Context) error {
+	if uc.cfg.MaximumShortUrlCount <= 0 {
+		return nil
+	}
+	count, err := uc.linkRepo.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count short URLs: %w", err)
+	}
+	if count >= uc.cfg.MaximumShortUrlCount {
+		return &QuotaExceededError{Quota: QuotaGlobalLinks, Limit: uc.cfg.MaximumShortUrlCount}
+	}
+	return nil
+}
+
+// checkOwnerLinks enforces the active link quota of a key, the status is
+// nil when the key has no such quota
+func (uc *shortUrlUseCase) checkOwnerLinks(ctx context.Context, caller *entity.APIKey) (*dto.QuotaStatus, error) {
+	limit := quotaLimit(caller.MaxLinks, uc.cfg.Quota.MaxLinks)
+	if limit == 0 {
+		return nil, nil
+	}
+	count, err := uc.linkRepo.CountByOwner(ctx, caller.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count short URLs: %w", err)
+	}
+	if count >= limit {
+		return nil, &QuotaExceededError{Quota: QuotaLinks, Limit: limit}
+	}
+	// The link being created takes one of the remaining slots
+	return &dto.QuotaStatus{LinksLimit: limit, LinksRemaining: limit - count - 1}, nil
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"strings"
+	"time"
+)
+
+// Redirect resolves where a visit of code goes
+func (uc *shortUrlUseCase) Redirect(ctx context.Context, code string, request *dto.RedirectRequest) (*dto.RedirectResponse, error) {
+	shortUrl, err := uc.getActiveLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if state := shortUrl.ScheduleState(now); state != entity.ScheduleActive {
+		return uc.offSchedule(shortUrl, state)
+	}
+	accessToken, accessExpiresAt, err := uc.unlock(shortUrl, request, now)
+	if err != nil {
+		return nil, err
+	}
+	location, err := uc.resolve(ctx, shortUrl, request)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkPolicy(location); err != nil {
+		return nil, err
+	}
+	// Counted last, so that only visits that are redirected use up clicks
+	if request.Probe {
+		err = uc.checkClicksLeft(ctx, shortUrl)
+	} else {
+		err = uc.consumeClick(ctx, shortUrl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dto.RedirectResponse{
+		ID:              shortUrl.Code,
+		Location:        location,
+		Status:          uc.redirectStatus(shortUrl.RedirectStatus),
+		AccessToken:     accessToken,
+		AccessExpiresAt: accessExpiresAt,
+		Routed:          len(shortUrl.RoutingRules) > 0,
+	}, nil
+}
+
+// PreviewShortUrl returns a link with the destination a visit would be
+// redirected to, without counting as a visit
+func (uc *shortUrlUseCase) PreviewShortUrl(ctx context.Context, code string, request *dto.RedirectRequest) (*dto.GetShortUrlResponse, error) {
+	shortUrl, err := uc.getActiveLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return nil, err
+	}
+	location, err := uc.resolve(ctx, shortUrl, request)
+	if err != nil {
+		return nil, err
+	}
+	response := uc.toGetResponse(shortUrl)
+	response.Preview = location
+	return response, nil
+}
+
+// resolve returns where a visit of link ends, routed by the rules of the
+// link and following links stored before short link destinations were
+// collapsed
+func (uc *shortUrlUseCase) resolve(ctx context.Context, link *entity.ShortURL, request *dto.RedirectRequest) (string, error) {
+	location, err := destination(link, uc.route(link, request, time.Now()), request)
+	if err != nil {
+		return "", err
+	}
+	return uc.followShortLinks(ctx, location)
+}
+
+// destination returns the URL a visit of link routed to location is
+// redirected to. Templates are filled from the visited path, other links
+// forward the visited path and query when they opt in and refuse a path
+// otherwise, so that mistyped links do not silently redirect.
+func destination(link *entity.ShortURL, location string, request *dto.RedirectRequest) (string, error) {
+	extraPath := strings.Trim(request.Path, "/")
+	if isTemplate(location) {
+		var segments []string
+		if extraPath != "" {
+			segments = strings.Split(extraPath, "/")
+		}
+		for _, segment := range segments {
+			if segment == "" || segment == "." || segment == ".." {
+				return "", ErrLinkNotFound
+			}
+		}
+		var err error
+		if location, err = expandTemplate(location, segments, request.Query); err != nil {
+			return "", err
+		}
+		extraPath = ""
+	} else if extraPath != "" && !link.ForwardPath {
+		return "", ErrLinkNotFound
+	}
+
+	if extraPath != "" || link.ForwardQuery && request.Query != "" {
+		location = forwardedURL(location, link, request)
+	}
+	return location, nil
+}
+
+// forwardedURL returns location with the path and query of the visit
+// passed through as link asks
+func forwardedURL(location string, link *entity.ShortURL, request *dto.RedirectRequest) string {
+	destination, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	if link.ForwardPath {
+		// Dot segments are dropped so a visit cannot climb out of the
+		// destination path
+		var segments []string
+		for _, segment := range strings.Split(request.Path, "/") {
+			if segment != "" && segment != "." && segment != ".." {
+				segments = append(segments, segment)
+			}
+		}
+		if len(segments) > 0 {
+			if strings.HasSuffix(request.Path, "/") {
+				segments[len(segments)-1] += "/"
+			}
+			destination = destination.JoinPath(segments...)
+		}
+	}
+
+	if link.ForwardQuery && request.Query != "" {
+		// Malformed pairs are skipped, the others still pass through
+		incoming, _ := url.ParseQuery(request.Query)
+		query := destination.Query()
+		for key, values := range incoming {
+			if _, ok := query[key]; ok && link.QueryConflict == entity.QueryConflictDestination {
+				continue
+			}
+			query[key] = values
+		}
+		destination.RawQuery = query.Encode()
+	}
+	return destination.String()
+}
+
+// validateRedirectStatus checks the redirect status requested for a link,
+// 0 standing for the server default
+func validateRedirectStatus(status int) error {
+	if status != 0 && !entity.IsRedirectStatus(status) {
+		return fmt.Errorf("%w: %d must be 301, 302, 307 or 308", ErrInvalidRedirectStatus, status)
+	}
+	return nil
+}
+
+// validateQueryConflict checks the query conflict rule requested for a
+// link, empty standing for incoming
+func validateQueryConflict(rule string) error {
+	switch rule {
+	case "", entity.QueryConflictIncoming, entity.QueryConflictDestination:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidQueryConflict, rule)
+}
+
+// redirectStatus returns the status a link choosing status redirects with
+func (uc *shortUrlUseCase) redirectStatus(status int) int {
+	if status != 0 {
+		return status
+	}
+	if uc.cfg.Server.RedirectStatus != 0 {
+		return uc.cfg.Server.RedirectStatus
+	}
+	return http.StatusFound
+}
+
+// queryConflict returns the query conflict rule a link applies
+func queryConflict(rule string) string {
+	if rule == "" {
+		return entity.QueryConflictIncoming
+	}
+	return rule
+}
+
+// redirectsAsRequested reports whether link redirects the way a create
+// request asks, so that it can be reused for the request. Password
+// protected, click limited, scheduled and routed links are never shared.
+func (uc *shortUrlUseCase) redirectsAsRequested(link *entity.ShortURL, request *dto.CreateRequest) bool {
+	return link.PasswordHash == "" && request.Password == "" &&
+		link.MaxClicks == 0 && request.MaxClicks == 0 &&
+		!link.HasSchedule() && !hasBound(request.NotBefore) && !hasBound(request.NotAfter) &&
+		len(link.RoutingRules) == 0 && len(request.RoutingRules) == 0 &&
+		uc.redirectStatus(link.RedirectStatus) == uc.redirectStatus(request.RedirectStatus) &&
+		link.ForwardQuery == request.ForwardQuery &&
+		link.ForwardPath == request.ForwardPath &&
+		queryConflict(link.QueryConflict) == queryConflict(request.QueryConflict)
+}
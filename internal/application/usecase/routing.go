@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/infrastructure/analytics"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Routing rules send the visits of a link to other destinations by device,
+// language, country and time of day. Rules are tried in order and the
+// first one matching the visit wins, visits no rule matches go to the
+// original URL. Path and query passthrough apply to every destination.
+
+// maxRoutingRules bounds the rules of a link, which are tried on every visit
+const maxRoutingRules = 20
+
+// clockLayout is the layout of the daily window bounds
+const clockLayout = "15:04"
+
+var (
+	languageTag = regexp.MustCompile(`^[a-z]{1,8}(-[a-z0-9]{1,8})*$`)
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// devices are the device classes rules can match
+var devices = []string{analytics.DeviceDesktop, analytics.DeviceMobile, analytics.DeviceTablet, analytics.DeviceBot}
+
+// locations caches the time zones of daily windows by name
+var locations sync.Map
+
+// CountryLocator resolves client addresses to ISO 3166-1 alpha-2 country
+// codes for routing rules, such as an analytics.GeoLocator
+type CountryLocator interface {
+	Country(ip string) string
+}
+
+// normalizeRoutingRules validates the routing rules requested for a link
+// and canonicalizes their values
+func (uc *shortUrlUseCase) normalizeRoutingRules(ctx context.Context, rules []dto.RoutingRule) ([]entity.RoutingRule, error) {
+	if len(rules) > maxRoutingRules {
+		return nil, fmt.Errorf("%w: at most %d rules", ErrInvalidRoutingRule, maxRoutingRules)
+	}
+	var normalized []entity.RoutingRule
+	for i, rule := range rules {
+		routingRule, err := uc.normalizeRoutingRule(ctx, rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		normalized = append(normalized, routingRule)
+	}
+	return normalized, nil
+}
+
+func (uc *shortUrlUseCase) normalizeRoutingRule(ctx context.Context, rule dto.RoutingRule) (entity.RoutingRule, error) {
+	var result entity.RoutingRule
+	for _, name := range rule.OS {
+		os, ok := analytics.OperatingSystem(strings.TrimSpace(name))
+		if !ok {
+			return result, fmt.Errorf("%w: unknown os %q", ErrInvalidRoutingRule, name)
+		}
+		result.OS = append(result.OS, os)
+	}
+	for _, device := range rule.Devices {
+		device = strings.ToLower(strings.TrimSpace(device))
+		if !slices.Contains(devices, device) {
+			return result, fmt.Errorf("%w: device %q must be desktop, mobile, tablet or bot", ErrInvalidRoutingRule, device)
+		}
+		result.Devices = append(result.Devices, device)
+	}
+	for _, language := range rule.Languages {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if !languageTag.MatchString(language) {
+			return result, fmt.Errorf("%w: %q is not a language tag", ErrInvalidRoutingRule, language)
+		}
+		result.Languages = append(result.Languages, language)
+	}
+	for _, country := range rule.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !countryCode.MatchString(country) {
+			return result, fmt.Errorf("%w: %q is not an ISO 3166-1 alpha-2 country code", ErrInvalidRoutingRule, country)
+		}
+		result.Countries = append(result.Countries, country)
+	}
+	if err := normalizeDailyWindow(rule, &result); err != nil {
+		return result, err
+	}
+	if len(result.OS)+len(result.Devices)+len(result.Languages)+len(result.Countries) == 0 && result.TimeFrom == "" {
+		return result, fmt.Errorf("%w: a rule needs at least one condition", ErrInvalidRoutingRule)
+	}
+
+	destination, err := uc.normalizeOriginalURL(ctx, rule.Destination)
+	if err != nil {
+		return result, fmt.Errorf("destination: %w", err)
+	}
+	if isTemplate(destination) {
+		return result, fmt.Errorf("%w: destination must not have placeholders", ErrInvalidRoutingRule)
+	}
+	result.Destination = destination
+	return result, nil
+}
+
+// normalizeDailyWindow validates the daily window of rule into result,
+// both bounds being required once one is set
+func normalizeDailyWindow(rule dto.RoutingRule, result *entity.RoutingRule) error {
+	if rule.TimeFrom == "" && rule.TimeTo == "" {
+		if rule.Timezone != "" {
+			return fmt.Errorf("%w: timezone needs time_from and time_to", ErrInvalidRoutingRule)
+		}
+		return nil
+	}
+	from, errFrom := time.Parse(clockLayout, rule.TimeFrom)
+	to, errTo := time.Parse(clockLayout, rule.TimeTo)
+	if errFrom != nil || errTo != nil {
+		return fmt.Errorf("%w: time_from and time_to must both be HH:MM", ErrInvalidRoutingRule)
+	}
+	if from.Equal(to) {
+		return fmt.Errorf("%w: time_from and time_to must differ", ErrInvalidRoutingRule)
+	}
+	if _, err := location(rule.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidRoutingRule, rule.Timezone)
+	}
+	result.TimeFrom, result.TimeTo, result.Timezone = from.Format(clockLayout), to.Format(clockLayout), rule.Timezone
+	return nil
+}
+
+// validateRoutingRules checks routing rules fit the original URL of their link
+func validateRoutingRules(originalURL string, rules []entity.RoutingRule) error {
+	// Rule destinations have no placeholders to take the visited path
+	if len(rules) > 0 && isTemplate(originalURL) {
+		return fmt.Errorf("%w: template links cannot have routing rules", ErrInvalidRoutingRule)
+	}
+	return nil
+}
+
+// visit holds what routing rules match on, worked out once a rule needs it
+type visit struct {
+	request   *dto.RedirectRequest
+	at        time.Time
+	geo       CountryLocator
+	userAgent *analytics.UserAgent
+	language  *string
+	country   *string
+}
+
+// route returns the destination of the first rule of link matching the
+// visit, the original URL when none does
+func (uc *shortUrlUseCase) route(link *entity.ShortURL, request *dto.RedirectRequest, now time.Time) string {
+	v := &visit{request: request, at: now, geo: uc.geo}
+	for _, rule := range link.RoutingRules {
+		if v.matches(rule) {
+			return rule.Destination
+		}
+	}
+	return link.OriginalURL
+}
+
+func (v *visit) matches(rule entity.RoutingRule) bool {
+	if len(rule.OS) > 0 && !slices.Contains(rule.OS, v.parsedUserAgent().OS) {
+		return false
+	}
+	if len(rule.Devices) > 0 && !slices.Contains(rule.Devices, v.parsedUserAgent().Device) {
+		return false
+	}
+	if len(rule.Languages) > 0 && !slices.ContainsFunc(rule.Languages, v.speaks) {
+		return false
+	}
+	if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, v.visitorCountry()) {
+		return false
+	}
+	return rule.TimeFrom == "" || inDailyWindow(rule, v.at)
+}
+
+func (v *visit) parsedUserAgent() analytics.UserAgent {
+	if v.userAgent == nil {
+		userAgent := analytics.ParseUserAgent(v.request.UserAgent)
+		v.userAgent = &userAgent
+	}
+	return *v.userAgent
+}
+
+// speaks reports whether the preferred language of the visit is language
+// or one of its regional variants
+func (v *visit) speaks(language string) bool {
+	if v.language == nil {
+		preferred := preferredLanguage(v.request.AcceptLanguage)
+		v.language = &preferred
+	}
+	return *v.language == language || strings.HasPrefix(*v.language, language+"-")
+}
+
+func (v *visit) visitorCountry() string {
+	if v.country == nil {
+		country := strings.ToUpper(v.request.Country)
+		if country == "" && v.geo != nil {
+			country = v.geo.Country(v.request.ClientIP)
+		}
+		v.country = &country
+	}
+	return *v.country
+}
+
+// preferredLanguage returns the lowercase tag of an Accept-Language header
+// with the highest quality, the first of equals, or empty
+func preferredLanguage(header string) string {
+	preferred, best := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" || quality <= best {
+			continue
+		}
+		preferred, best = tag, quality
+	}
+	return preferred
+}
+
+// inDailyWindow reports whether at falls in the daily window of rule
+func inDailyWindow(rule entity.RoutingRule, at time.Time) bool {
+	zone, err := location(rule.Timezone)
+	if err != nil {
+		return false
+	}
+	from, _ := time.Parse(clockLayout, rule.TimeFrom)
+	to, _ := time.Parse(clockLayout, rule.TimeTo)
+	local := at.In(zone)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	if start < end {
+		return start <= minute && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// location loads the time zone name, UTC when empty
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if cached, ok := locations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, zone)
+	return zone, nil
+}
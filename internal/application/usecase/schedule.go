@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"time"
+)
+
+// Scheduled links only redirect within their activation window. Visits
+// outside it go to the fallback URL of the link or of the server, and
+// without one are told the link is coming soon or has ended.
+
+// ScheduledLinkError is returned when a link without fallback is visited
+// before its activation window, its message is the one configured for
+// visitors
+type ScheduledLinkError struct {
+	NotBefore time.Time
+	Message   string
+}
+
+func (e *ScheduledLinkError) Error() string {
+	return e.Message
+}
+
+func (e *ScheduledLinkError) Unwrap() error {
+	return ErrLinkNotYetActive
+}
+
+// parseSchedule applies the bounds a request gives to the activation
+// window of a link, either bound being optional. Bounds are RFC 3339
+// timestamps or dates, a not_after date covering its whole day. Nil values
+// keep the current bound and empty ones remove it.
+func parseSchedule(notBefore, notAfter *string, currentBefore, currentAfter *time.Time) (*time.Time, *time.Time, error) {
+	var err error
+	if notBefore != nil {
+		if currentBefore, err = parseQueryTime(*notBefore, "not_before", false, ErrInvalidSchedule); err != nil {
+			return nil, nil, err
+		}
+	}
+	if notAfter != nil {
+		if currentAfter, err = parseQueryTime(*notAfter, "not_after", true, ErrInvalidSchedule); err != nil {
+			return nil, nil, err
+		}
+	}
+	if currentBefore != nil && currentAfter != nil && !currentAfter.After(*currentBefore) {
+		return nil, nil, fmt.Errorf("%w: not_after must be after not_before", ErrInvalidSchedule)
+	}
+	return currentBefore, currentAfter, nil
+}
+
+// hasBound reports whether a request sets a bound of the activation window
+func hasBound(value *string) bool {
+	return value != nil && *value != ""
+}
+
+// normalizeFallbackURL validates the fallback URL of a link like a
+// destination, empty standing for the server default
+func (uc *shortUrlUseCase) normalizeFallbackURL(ctx context.Context, raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	fallback, err := uc.normalizeOriginalURL(ctx, raw)
+	if err != nil {
+		return "", fmt.Errorf("fallback_url: %w", err)
+	}
+	if isTemplate(fallback) {
+		return "", fmt.Errorf("%w: fallback_url must not have placeholders", ErrInvalidSchedule)
+	}
+	return fallback, nil
+}
+
+// offSchedule answers a visit of link outside its activation window
+func (uc *shortUrlUseCase) offSchedule(link *entity.ShortURL, state string) (*dto.RedirectResponse, error) {
+	fallback := link.FallbackURL
+	if fallback == "" {
+		fallback = uc.cfg.Schedule.FallbackURL
+	}
+	if fallback != "" {
+		if err := uc.checkPolicy(fallback); err != nil {
+			return nil, err
+		}
+		// Always temporary, the link redirects elsewhere once live
+		return &dto.RedirectResponse{ID: link.Code, Location: fallback, Status: http.StatusFound, Fallback: true}, nil
+	}
+	if state == entity.ScheduleEnded {
+		return nil, ErrLinkEnded
+	}
+	message := uc.cfg.Schedule.ComingSoonMessage
+	if message == "" {
+		message = config.DefaultComingSoonMessage
+	}
+	return nil, &ScheduledLinkError{NotBefore: *link.NotBefore, Message: message}
+}
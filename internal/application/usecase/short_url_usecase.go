@@ -2,107 +2,356 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"shorter-rest-api/internal/config"
 	"shorter-rest-api/internal/domain/dto"
 	"shorter-rest-api/internal/domain/entity"
-	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/domain/repository"
 	"shorter-rest-api/internal/infrastructure/utils"
 	"strings"
 	"time"
 )
 
-// ShortUrlUseCase defines the interface for shortUrl use cases
+const (
+	// maxCodeAttempts bounds the retries when a generated code is already taken
+	maxCodeAttempts = 10
+	// defaultIdempotencyKeyTTL is used when no idempotency key TTL is configured
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyClaimTTL bounds how long a claimed idempotency key stays in
+	// flight, so the key frees itself when its request dies before answering
+	idempotencyClaimTTL = time.Minute
+	// timeLayout formats the timestamps of the response DTOs
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// ShortUrlUseCase defines the interface for shortUrl use cases. Codes
+// address links on the domain of the context, see WithDomain.
 type ShortUrlUseCase interface {
 	GetShortUrlByCode(ctx context.Context, code string) (*dto.GetShortUrlResponse, error)
-	CreateShortUrl(ctx context.Context, url *dto.CreateRequest) (*dto.CreateResponse, error)
-	ValidateDuplicateShortUrl(originalUrl string) (bool, error)
+	// Redirect resolves the destination of a visit of code
+	Redirect(ctx context.Context, code string, request *dto.RedirectRequest) (*dto.RedirectResponse, error)
+	// PreviewShortUrl returns a link with the destination of a visit, which is not counted
+	PreviewShortUrl(ctx context.Context, code string, request *dto.RedirectRequest) (*dto.GetShortUrlResponse, error)
+	ListShortUrls(ctx context.Context, request *dto.ListRequest) (*dto.ListResponse, error)
+	UpdateExpiration(ctx context.Context, code string, request *dto.UpdateExpirationRequest) (*dto.GetShortUrlResponse, error)
+	UpdateShortUrl(ctx context.Context, code string, request *dto.UpdateRequest) (*dto.GetShortUrlResponse, error)
+	DeleteShortUrl(ctx context.Context, code string, permanent bool) error
+	RestoreShortUrl(ctx context.Context, code string) (*dto.GetShortUrlResponse, error)
+	// CreateShortUrl returns the short link for the request and whether a new code was minted
+	CreateShortUrl(ctx context.Context, url *dto.CreateRequest) (*dto.CreateResponse, bool, error)
+	// ResolveDomain validates a requested short link domain for WithDomain
+	ResolveDomain(domain string) (string, error)
+	// DomainForHost returns the domain of the links served to a request Host
+	DomainForHost(host string) string
 }
 
 type shortUrlUseCase struct {
-	cacheService cache.IRedisCache
-	cfg          *config.Config
+	linkRepo        repository.LinkRepository
+	idempotencyRepo repository.IdempotencyRepository
+	quotaRepo       repository.QuotaRepository
+	policy          DestinationPolicy
+	geo             CountryLocator
+	domains         publicDomains
+	accessSecret    []byte
+	cfg             *config.Config
 }
 
-// NewShortUrlUseCase creates a new shortUrl use case
-func NewShortUrlUseCase(config *config.Config, cacheService cache.IRedisCache) ShortUrlUseCase {
+// NewShortUrlUseCase creates a new shortUrl use case, a nil policy lets
+// every destination through and a nil geo matches no country rules
+func NewShortUrlUseCase(config *config.Config, linkRepo repository.LinkRepository, idempotencyRepo repository.IdempotencyRepository, quotaRepo repository.QuotaRepository, policy DestinationPolicy, geo CountryLocator) ShortUrlUseCase {
 	return &shortUrlUseCase{
-		cacheService: cacheService,
-		cfg:          config,
+		linkRepo:        linkRepo,
+		idempotencyRepo: idempotencyRepo,
+		quotaRepo:       quotaRepo,
+		policy:          policy,
+		geo:             geo,
+		domains:         newPublicDomains(config),
+		accessSecret:    newAccessSecret(config.Password.Secret),
+		cfg:             config,
 	}
 }
 
-func (uc *shortUrlUseCase) ValidateDuplicateShortUrl(originalUrl string) (bool, error) {
+func (uc *shortUrlUseCase) GetShortUrlByCode(ctx context.Context, code string) (*dto.GetShortUrlResponse, error) {
 
 	// Get short URL by code
-	isExist, err := uc.cacheService.Exists(originalUrl)
-	if err != nil && !strings.Contains(err.Error(), "redigo: nil returned") {
-		return false, fmt.Errorf("failed to find short url: %w", err)
+	shortUrl, err := uc.getActiveLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return nil, err
 	}
-	if !isExist {
-		return true, nil // No duplicate found
+
+	response := uc.toGetResponse(shortUrl)
+	if shortUrl.MaxClicks > 0 {
+		remaining, err := uc.remainingClicks(ctx, shortUrl)
+		if err != nil {
+			return nil, err
+		}
+		response.RemainingClicks = &remaining
 	}
-	return isExist, nil
+	return response, nil
 }
 
-func (uc *shortUrlUseCase) GetShortUrlByCode(ctx context.Context, code string) (*dto.GetShortUrlResponse, error) {
+func (uc *shortUrlUseCase) ResolveDomain(domain string) (string, error) {
+	return uc.domains.resolve(domain)
+}
 
-	// Get short URL by code
-	shortUrl, err := uc.cacheService.Get(code)
+func (uc *shortUrlUseCase) DomainForHost(host string) string {
+	return uc.domains.forHost(host)
+}
+
+// toGetResponse maps a short URL to its response DTO
+func (uc *shortUrlUseCase) toGetResponse(shortUrl *entity.ShortURL) *dto.GetShortUrlResponse {
+	response := &dto.GetShortUrlResponse{
+		ID:             shortUrl.Code,
+		Domain:         shortUrl.Domain,
+		ShortUrl:       uc.domains.shortURL(shortUrl),
+		OriginalUrl:    shortUrl.OriginalURL,
+		Title:          shortUrl.Title,
+		Tags:           shortUrl.Tags,
+		CreatedAt:      shortUrl.CreatedAt.Format(timeLayout),
+		OwnerID:        shortUrl.OwnerID,
+		RedirectStatus: uc.redirectStatus(shortUrl.RedirectStatus),
+		ForwardQuery:   shortUrl.ForwardQuery,
+		ForwardPath:    shortUrl.ForwardPath,
+		QueryConflict:  queryConflict(shortUrl.QueryConflict),
+		Template:       isTemplate(shortUrl.OriginalURL),
+		Protected:      shortUrl.PasswordHash != "",
+		MaxClicks:      shortUrl.MaxClicks,
+		FallbackURL:    shortUrl.FallbackURL,
+		ScheduleState:  shortUrl.ScheduleState(time.Now()),
+	}
+	for _, rule := range shortUrl.RoutingRules {
+		response.RoutingRules = append(response.RoutingRules, dto.RoutingRule(rule))
+	}
+	if shortUrl.NotBefore != nil {
+		response.NotBefore = shortUrl.NotBefore.Format(timeLayout)
+	}
+	if shortUrl.NotAfter != nil {
+		response.NotAfter = shortUrl.NotAfter.Format(timeLayout)
+	}
+	if shortUrl.UpdatedAt != nil {
+		response.UpdatedAt = shortUrl.UpdatedAt.Format(timeLayout)
+	}
+	if shortUrl.ExpiresAt != nil {
+		response.ExpiresAt = shortUrl.ExpiresAt.Format(timeLayout)
+	}
+	return response
+}
+
+// CreateShortUrl creates a new shortUrl, or returns the live one already
+// assigned to the same original URL unless a fresh code is forced
+func (uc *shortUrlUseCase) CreateShortUrl(ctx context.Context, shortUrl *dto.CreateRequest) (*dto.CreateResponse, bool, error) {
+	originalURL, err := uc.normalizeOriginalURL(ctx, shortUrl.OriginalUrl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find short url: %w", err)
+		return nil, false, err
+	}
+	domain := DomainFrom(ctx)
+	if shortUrl.Domain != "" {
+		var err error
+		if domain, err = uc.domains.resolve(shortUrl.Domain); err != nil {
+			return nil, false, err
+		}
+	}
+	if shortUrl.IdempotencyKey == "" {
+		return uc.createOrReuse(ctx, shortUrl, domain, originalURL)
 	}
 
-	// Map to response DTO
-	response := &dto.GetShortUrlResponse{
-		ID:          shortUrl.Code,
-		OriginalUrl: shortUrl.OriginalURL,
-		CreatedAt:   shortUrl.CreatedAt.Format("2006-01-02 15:04:05"),
+	// Claim the idempotency key before minting so concurrent retries
+	// of the same request can never create two codes
+	ttl := time.Duration(uc.cfg.IdempotencyKeyTTL) * time.Second
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	// Keys are scoped to the caller so teams cannot replay each other's requests
+	idempotencyKey := shortUrl.IdempotencyKey
+	if owner := callerID(ctx); owner != "" {
+		idempotencyKey = owner + ":" + idempotencyKey
+	}
+	// The claim only lives as long as a request may take, the key is kept
+	// for the full TTL once the response is recorded
+	code, claimed, err := uc.idempotencyRepo.ClaimIdempotencyKey(ctx, idempotencyKey, idempotencyClaimTTL)
+	if err != nil {
+		return nil, false, err
+	}
+	if !claimed {
+		return uc.replayIdempotentCreate(ctx, code, domain, originalURL)
 	}
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// Released even when the caller went away, so a retry can go ahead
+		if err := uc.idempotencyRepo.ReleaseIdempotencyKey(context.WithoutCancel(ctx), idempotencyKey); err != nil {
+			log.Printf("Failed to release idempotency key: %v", err)
+		}
+	}()
 
-	return response, nil
+	response, created, err := uc.createOrReuse(ctx, shortUrl, domain, originalURL)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := uc.idempotencyRepo.CompleteIdempotencyKey(context.WithoutCancel(ctx), idempotencyKey, entity.LinkKey(response.Domain, response.ID), ttl); err != nil {
+		log.Printf("Failed to complete idempotency key: %v", err)
+	}
+	completed = true
+	return response, created, nil
+}
+
+// replayIdempotentCreate answers a retried request with the link its key produced
+func (uc *shortUrlUseCase) replayIdempotentCreate(ctx context.Context, code, domain, originalURL string) (*dto.CreateResponse, bool, error) {
+	if code == "" {
+		return nil, false, ErrIdempotencyKeyInProgress
+	}
+	existing, err := uc.linkRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find short url: %w", err)
+	}
+	if existing.OriginalURL != originalURL || existing.Domain != domain {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	return uc.toCreateResponse(existing), false, nil
 }
 
-// CreateShortUrl creates a new shortUrl
-func (uc *shortUrlUseCase) CreateShortUrl(ctx context.Context, shortUrl *dto.CreateRequest) (*dto.CreateResponse, error) {
+func (uc *shortUrlUseCase) createOrReuse(ctx context.Context, shortUrl *dto.CreateRequest, domain, originalURL string) (*dto.CreateResponse, bool, error) {
+	if err := validateRedirectStatus(shortUrl.RedirectStatus); err != nil {
+		return nil, false, err
+	}
+	if err := validateQueryConflict(shortUrl.QueryConflict); err != nil {
+		return nil, false, err
+	}
+	if err := validateTemplate(originalURL, shortUrl.ForwardPath); err != nil {
+		return nil, false, err
+	}
+	if err := validateMaxClicks(shortUrl.MaxClicks); err != nil {
+		return nil, false, err
+	}
+	notBefore, notAfter, err := parseSchedule(shortUrl.NotBefore, shortUrl.NotAfter, nil, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	fallbackURL, err := uc.normalizeFallbackURL(ctx, shortUrl.FallbackURL)
+	if err != nil {
+		return nil, false, err
+	}
+	routingRules, err := uc.normalizeRoutingRules(ctx, shortUrl.RoutingRules)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validateRoutingRules(originalURL, routingRules); err != nil {
+		return nil, false, err
+	}
+	var passwordHash string
+	if shortUrl.Password != "" {
+		var err error
+		if passwordHash, err = hashPassword(shortUrl.Password); err != nil {
+			return nil, false, err
+		}
+	}
+	if shortUrl.Alias != "" {
+		if err := uc.validateAlias(shortUrl.Alias); err != nil {
+			return nil, false, err
+		}
+	} else if !shortUrl.ForceNew {
+		// Only the caller's own links are reused, a link of another team
+		// could not be managed by the caller, nor one redirecting otherwise.
+		// The reverse index keeps one entry per owner for that reason.
+		existing, err := uc.linkRepo.GetByOriginalURL(ctx, domain, callerID(ctx), originalURL)
+		if err == nil && !existing.IsDeleted() && !existing.IsExpired(time.Now()) && uc.redirectsAsRequested(existing, shortUrl) {
+			return uc.toCreateResponse(existing), false, nil
+		}
+		if err != nil && !errors.Is(err, repository.ErrLinkNotFound) {
+			return nil, false, fmt.Errorf("failed to find short url: %w", err)
+		}
+	}
 
-	// check maximum short URL count follow configure from
-	// initialization simplest will hardcode is 1 million saved keys
-	count, err := uc.cacheService.CountKeysByPattern("short_urls:*")
+	now := time.Now()
+	expiresAt, ok, err := uc.resolveExpiry(shortUrl.ExpiresAt, shortUrl.TTLSeconds, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count short URLs: %w", err)
+		return nil, false, err
 	}
-	if count >= uc.cfg.MaximumShortUrlCount {
-		return nil, fmt.Errorf("maximum short URL count reached: %d", uc.cfg.MaximumShortUrlCount)
+	if !ok {
+		expiresAt = uc.defaultExpiry(now)
+	}
+
+	quota, err := uc.reserveCreate(ctx, now)
+	if err != nil {
+		return nil, false, err
 	}
 	// Create a new short URL entity
 	newShortUrl := &entity.ShortURL{
-		OriginalURL: shortUrl.OriginalUrl,
-		CreatedAt:   time.Now(), // Set the current time as CreatedAt
+		Domain:         domain,
+		OriginalURL:    originalURL,
+		Title:          strings.TrimSpace(shortUrl.Title),
+		Tags:           normalizeTags(shortUrl.Tags),
+		CreatedAt:      now, // Set the current time as CreatedAt
+		ExpiresAt:      expiresAt,
+		OwnerID:        callerID(ctx),
+		RedirectStatus: shortUrl.RedirectStatus,
+		ForwardQuery:   shortUrl.ForwardQuery,
+		ForwardPath:    shortUrl.ForwardPath,
+		QueryConflict:  shortUrl.QueryConflict,
+		PasswordHash:   passwordHash,
+		MaxClicks:      shortUrl.MaxClicks,
+		NotBefore:      notBefore,
+		NotAfter:       notAfter,
+		FallbackURL:    fallbackURL,
+		RoutingRules:   routingRules,
 	}
 
-	// generate code for the short URL
-	for {
-		shortCode := utils.GenerateShortCode() // Assume this function generates a random short code
-		newShortUrl.Code = shortCode
-		exists, _ := uc.cacheService.Exists(fmt.Sprintf("short_urls:%s", newShortUrl.Code))
-		if !exists {
-			break
-		}
+	if err := uc.insert(ctx, newShortUrl, shortUrl.Alias, uc.storageTTL(expiresAt, now), uc.linkLimits(ctx)); err != nil {
+		uc.releaseCreate(ctx, quota, now)
+		return nil, false, err
 	}
+	response := uc.toCreateResponse(newShortUrl)
+	response.Quota = quota
+	return response, true, nil
+}
 
-	// Store the new short URL in the cache
-	if err := uc.cacheService.Set(fmt.Sprintf("short_urls:%s", newShortUrl.Code), *newShortUrl, uc.cfg.Expiration); err != nil {
-		return nil, fmt.Errorf("failed to create short URL: %w", err)
+// insert stores a new link under alias, or under a generated code when
+// no alias is requested, within the active link limits
+func (uc *shortUrlUseCase) insert(ctx context.Context, newShortUrl *entity.ShortURL, alias string, ttl time.Duration, limits repository.LinkLimits) error {
+	// Reserve the requested alias as is, it is never replaced by another code
+	if alias != "" {
+		newShortUrl.Code = alias
+		err := uc.linkRepo.Create(ctx, newShortUrl, ttl, limits)
+		if errors.Is(err, repository.ErrCodeAlreadyExists) {
+			return fmt.Errorf("%w: %s", ErrAliasTaken, alias)
+		}
+		if quotaErr := linkLimitError(err, limits); quotaErr != nil {
+			return quotaErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create short URL: %w", err)
+		}
+		return nil
 	}
-	// Store the original URL in the cache with the short code as the key
-	if err := uc.cacheService.Set(newShortUrl.OriginalURL, *newShortUrl, uc.cfg.Expiration); err != nil {
-		return nil, fmt.Errorf("failed to create short URL: %w", err)
+
+	// Generate a code and reserve it atomically together with the original
+	// URL reverse entry, retrying with a fresh code on collision
+	for attempt := 1; ; attempt++ {
+		newShortUrl.Code = utils.GenerateShortCode()
+		err := uc.linkRepo.Create(ctx, newShortUrl, ttl, limits)
+		if err == nil {
+			return nil
+		}
+		if quotaErr := linkLimitError(err, limits); quotaErr != nil {
+			return quotaErr
+		}
+		if !errors.Is(err, repository.ErrCodeAlreadyExists) || attempt >= maxCodeAttempts {
+			return fmt.Errorf("failed to create short URL: %w", err)
+		}
 	}
+}
 
+func (uc *shortUrlUseCase) toCreateResponse(shortUrl *entity.ShortURL) *dto.CreateResponse {
 	return &dto.CreateResponse{
-		ID:       newShortUrl.Code,
-		ShortUrl: fmt.Sprintf("http://localhost:%s/shortlinks/%s", uc.cfg.Server.Port, newShortUrl.Code),
-	}, nil
-
+		ID:       shortUrl.Code,
+		Domain:   shortUrl.Domain,
+		ShortUrl: uc.domains.shortURL(shortUrl),
+	}
 }
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"strings"
+	"time"
+)
+
+// statsRanges holds the default and the longest range of each granularity
+var statsRanges = map[entity.Granularity]struct{ fallback, max time.Duration }{
+	entity.GranularityMinute: {time.Hour, 24 * time.Hour},
+	entity.GranularityHour:   {24 * time.Hour, 31 * 24 * time.Hour},
+	entity.GranularityDay:    {30 * 24 * time.Hour, 366 * 24 * time.Hour},
+}
+
+// ClickRecorder stores click events off the request path
+type ClickRecorder interface {
+	// Record queues an event, reporting false when it had to be dropped
+	Record(event *entity.ClickEvent) bool
+}
+
+// StatsUseCase represents the click analytics use case interface
+type StatsUseCase interface {
+	// TrackClick records a redirect of code on click.Domain without waiting for storage
+	TrackClick(code string, click *dto.ClickRequest)
+	GetClickStats(ctx context.Context, code string, request *dto.StatsRequest) (*dto.StatsResponse, error)
+}
+
+type statsUseCase struct {
+	linkRepo  repository.LinkRepository
+	statsRepo repository.StatsRepository
+	recorder  ClickRecorder
+}
+
+// NewStatsUseCase creates a new click analytics use case
+func NewStatsUseCase(linkRepo repository.LinkRepository, statsRepo repository.StatsRepository, recorder ClickRecorder) StatsUseCase {
+	return &statsUseCase{
+		linkRepo:  linkRepo,
+		statsRepo: statsRepo,
+		recorder:  recorder,
+	}
+}
+
+func (uc *statsUseCase) TrackClick(code string, click *dto.ClickRequest) {
+	uc.recorder.Record(&entity.ClickEvent{
+		Code:       entity.LinkKey(click.Domain, code),
+		OccurredAt: time.Now().UTC(),
+		ClientIP:   click.ClientIP,
+		UserAgent:  click.UserAgent,
+		Referrer:   click.Referrer,
+	})
+}
+
+// GetClickStats returns the click activity of a link over the requested range.
+// Stats stay readable after the link expires, but not once it is deleted,
+// and only by the owner of the link.
+func (uc *statsUseCase) GetClickStats(ctx context.Context, code string, request *dto.StatsRequest) (*dto.StatsResponse, error) {
+	query, err := toStatsQuery(linkKey(ctx, code), request, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := uc.linkRepo.GetByCode(ctx, query.Code)
+	if errors.Is(err, ErrLinkNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find short url: %w", err)
+	}
+	if link.IsDeleted() {
+		return nil, ErrLinkDeleted
+	}
+	if err := authorizeLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	stats, err := uc.statsRepo.GetClickStats(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+	return toStatsResponse(code, query, stats), nil
+}
+
+func toStatsQuery(key string, request *dto.StatsRequest, now time.Time) (repository.StatsQuery, error) {
+	query := repository.StatsQuery{Code: key, Granularity: entity.Granularity(strings.ToLower(request.Granularity))}
+	if query.Granularity == "" {
+		query.Granularity = entity.GranularityDay
+	}
+	ranges, ok := statsRanges[query.Granularity]
+	if !ok {
+		return query, fmt.Errorf("%w: granularity must be minute, hour or day", ErrInvalidStatsQuery)
+	}
+
+	to, err := parseQueryTime(request.To, "to", true, ErrInvalidStatsQuery)
+	if err != nil {
+		return query, err
+	}
+	from, err := parseQueryTime(request.From, "from", false, ErrInvalidStatsQuery)
+	if err != nil {
+		return query, err
+	}
+	query.To = now.UTC()
+	if to != nil {
+		query.To = to.UTC()
+	}
+	query.From = query.To.Add(-ranges.fallback)
+	if from != nil {
+		query.From = from.UTC()
+	}
+
+	if query.From.After(query.To) {
+		return query, fmt.Errorf("%w: from must not be after to", ErrInvalidStatsQuery)
+	}
+	if query.To.Sub(query.From) > ranges.max {
+		return query, fmt.Errorf("%w: %s granularity covers at most %s", ErrInvalidStatsQuery, query.Granularity, ranges.max)
+	}
+	query.From = query.Granularity.BucketStart(query.From)
+	query.To = query.Granularity.BucketStart(query.To)
+	return query, nil
+}
+
+func toStatsResponse(code string, query repository.StatsQuery, stats *entity.ClickStats) *dto.StatsResponse {
+	response := &dto.StatsResponse{
+		ID:          code,
+		From:        query.From.Format(timeLayout),
+		To:          query.To.Add(query.Granularity.Duration() - time.Second).Format(timeLayout),
+		Granularity: string(query.Granularity),
+		Series:      make([]dto.StatsBucket, 0, len(stats.Series)),
+		Referrers:   stats.Breakdowns[entity.DimensionReferrer],
+		Browsers:    stats.Breakdowns[entity.DimensionBrowser],
+		Countries:   stats.Breakdowns[entity.DimensionCountry],
+
+		UniqueVisitors: stats.UniqueVisitors,
+		DailyUniques:   make([]dto.VisitorsBucket, 0, len(stats.DailyVisitors)),
+	}
+	for _, bucket := range stats.Series {
+		response.TotalClicks += bucket.Clicks
+		response.Series = append(response.Series, dto.StatsBucket{Start: bucket.Start.Format(timeLayout), Clicks: bucket.Clicks})
+	}
+	for _, bucket := range stats.DailyVisitors {
+		response.DailyUniques = append(response.DailyUniques, dto.VisitorsBucket{Start: bucket.Start.Format(timeLayout), Visitors: bucket.Visitors})
+	}
+	return response
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Template links are go-links whose destination has placeholders filled
+// from the visit: {1}, {2}... take the path segments following the code,
+// {*} the segments left after the numbered ones and {query} the raw query.
+// /jira/PROJ-123 of a link to https://jira.example/browse/{1} redirects to
+// https://jira.example/browse/PROJ-123.
+const (
+	placeholderRest  = "*"
+	placeholderQuery = "query"
+	// maxPlaceholder bounds the numbered placeholders
+	maxPlaceholder = 20
+)
+
+var templatePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// splitAuthority splits a URL into its scheme, host part and the rest
+func splitAuthority(raw string) (scheme, authority, rest string, ok bool) {
+	scheme, authority, ok = strings.Cut(raw, "://")
+	if i := strings.IndexAny(authority, "/?#"); i >= 0 {
+		authority, rest = authority[:i], authority[i:]
+	}
+	return scheme, authority, rest, ok
+}
+
+// validateTemplate checks the placeholders of a destination, which must
+// only be known ones and {query} only in the query. Placeholders in the
+// host are refused by normalizeOriginalURL.
+func validateTemplate(originalURL string, forwardPath bool) error {
+	if !strings.ContainsAny(originalURL, "{}") {
+		return nil
+	}
+	queryStart := templateQueryStart(originalURL)
+	for _, match := range templatePlaceholder.FindAllStringSubmatchIndex(originalURL, -1) {
+		name := originalURL[match[2]:match[3]]
+		switch {
+		case name == placeholderQuery:
+			if match[0] < queryStart {
+				return fmt.Errorf("%w: {query} must be in the query", ErrInvalidTemplate)
+			}
+		case name == placeholderRest:
+		default:
+			if n, err := strconv.Atoi(name); err != nil || n < 1 || n > maxPlaceholder || name != strconv.Itoa(n) {
+				return fmt.Errorf("%w: unknown placeholder {%s}, use {1} to {%d}, {*} or {query}", ErrInvalidTemplate, name, maxPlaceholder)
+			}
+		}
+	}
+	sample := templatePlaceholder.ReplaceAllString(originalURL, "x")
+	if strings.ContainsAny(sample, "{}") {
+		return fmt.Errorf("%w: unbalanced braces, escape literal ones as %%7B and %%7D", ErrInvalidTemplate)
+	}
+	parsed, err := url.Parse(sample)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%w: not an absolute URL", ErrInvalidTemplate)
+	}
+	if forwardPath {
+		return fmt.Errorf("%w: templates take the path themselves, forward_path does not apply", ErrInvalidTemplate)
+	}
+	return nil
+}
+
+// isTemplate reports whether a destination has placeholders
+func isTemplate(originalURL string) bool {
+	return templatePlaceholder.MatchString(originalURL)
+}
+
+// templateQueryStart returns the offset of the query or fragment of a
+// template, where values are query escaped instead of path escaped
+func templateQueryStart(template string) int {
+	if i := strings.IndexAny(template, "?#"); i >= 0 {
+		return i
+	}
+	return len(template)
+}
+
+// expandTemplate fills the placeholders of template from the path segments
+// and raw query of a visit
+func expandTemplate(template string, segments []string, rawQuery string) (string, error) {
+	matches := templatePlaceholder.FindAllStringSubmatchIndex(template, -1)
+	needed, rest := 0, false
+	for _, match := range matches {
+		switch name := template[match[2]:match[3]]; name {
+		case placeholderRest:
+			rest = true
+		case placeholderQuery:
+		default:
+			n, _ := strconv.Atoi(name)
+			needed = max(needed, n)
+		}
+	}
+	if len(segments) < needed {
+		return "", fmt.Errorf("%w: %d path segments expected after the code, got %d", ErrMissingTemplateArgs, needed, len(segments))
+	}
+	if len(segments) > needed && !rest {
+		return "", ErrLinkNotFound
+	}
+
+	queryStart := templateQueryStart(template)
+	var expanded strings.Builder
+	last := 0
+	for _, match := range matches {
+		expanded.WriteString(template[last:match[0]])
+		last = match[1]
+		escape := url.PathEscape
+		if match[0] >= queryStart {
+			escape = url.QueryEscape
+		}
+		switch name := template[match[2]:match[3]]; name {
+		case placeholderQuery:
+			expanded.WriteString(rawQuery)
+		case placeholderRest:
+			values := segments[min(needed, len(segments)):]
+			if match[0] >= queryStart {
+				expanded.WriteString(escape(strings.Join(values, "/")))
+				break
+			}
+			for i, value := range values {
+				if i > 0 {
+					expanded.WriteByte('/')
+				}
+				expanded.WriteString(escape(value))
+			}
+		default:
+			n, _ := strconv.Atoi(name)
+			expanded.WriteString(escape(segments[n-1]))
+		}
+	}
+	expanded.WriteString(template[last:])
+	return expanded.String(), nil
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"strings"
+	"time"
+)
+
+// UpdateShortUrl changes the destination and metadata of a link
+func (uc *shortUrlUseCase) UpdateShortUrl(ctx context.Context, code string, request *dto.UpdateRequest) (*dto.GetShortUrlResponse, error) {
+	shortUrl, err := uc.getActiveLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return nil, err
+	}
+
+	if request.OriginalUrl != nil {
+		originalURL, err := uc.normalizeOriginalURL(ctx, *request.OriginalUrl)
+		if err != nil {
+			return nil, err
+		}
+		shortUrl.OriginalURL = originalURL
+	}
+	if request.Title != nil {
+		shortUrl.Title = strings.TrimSpace(*request.Title)
+	}
+	if request.Tags != nil {
+		shortUrl.Tags = normalizeTags(*request.Tags)
+	}
+	if request.RedirectStatus != nil {
+		if err := validateRedirectStatus(*request.RedirectStatus); err != nil {
+			return nil, err
+		}
+		shortUrl.RedirectStatus = *request.RedirectStatus
+	}
+	if request.ForwardQuery != nil {
+		shortUrl.ForwardQuery = *request.ForwardQuery
+	}
+	if request.ForwardPath != nil {
+		shortUrl.ForwardPath = *request.ForwardPath
+	}
+	if request.QueryConflict != nil {
+		if err := validateQueryConflict(*request.QueryConflict); err != nil {
+			return nil, err
+		}
+		shortUrl.QueryConflict = *request.QueryConflict
+	}
+	if err := validateTemplate(shortUrl.OriginalURL, shortUrl.ForwardPath); err != nil {
+		return nil, err
+	}
+	if request.Password != nil {
+		shortUrl.PasswordHash = ""
+		if *request.Password != "" {
+			if shortUrl.PasswordHash, err = hashPassword(*request.Password); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Redirects already served keep counting against a changed limit
+	if request.MaxClicks != nil {
+		if err := validateMaxClicks(*request.MaxClicks); err != nil {
+			return nil, err
+		}
+		shortUrl.MaxClicks = *request.MaxClicks
+	}
+	if shortUrl.NotBefore, shortUrl.NotAfter, err = parseSchedule(request.NotBefore, request.NotAfter, shortUrl.NotBefore, shortUrl.NotAfter); err != nil {
+		return nil, err
+	}
+	if request.FallbackURL != nil {
+		if shortUrl.FallbackURL, err = uc.normalizeFallbackURL(ctx, *request.FallbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if request.RoutingRules != nil {
+		if shortUrl.RoutingRules, err = uc.normalizeRoutingRules(ctx, *request.RoutingRules); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateRoutingRules(shortUrl.OriginalURL, shortUrl.RoutingRules); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shortUrl.UpdatedAt = &now
+	if err := uc.linkRepo.Update(ctx, shortUrl, uc.storageTTL(shortUrl.ExpiresAt, now)); err != nil {
+		return nil, fmt.Errorf("failed to update short url: %w", err)
+	}
+	return uc.toGetResponse(shortUrl), nil
+}
+
+// DeleteShortUrl soft deletes a link, keeping it restorable for the
+// retention window, or removes it right away when permanent is set
+func (uc *shortUrlUseCase) DeleteShortUrl(ctx context.Context, code string, permanent bool) error {
+	shortUrl, err := uc.linkRepo.GetByCode(ctx, linkKey(ctx, code))
+	if err != nil {
+		return fmt.Errorf("failed to find short url: %w", err)
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return err
+	}
+
+	retention := time.Duration(uc.cfg.DeletedLinkRetention) * time.Second
+	if permanent || retention <= 0 {
+		if err := uc.linkRepo.Delete(ctx, linkKey(ctx, code)); err != nil {
+			return fmt.Errorf("failed to delete short url: %w", err)
+		}
+		return nil
+	}
+	if shortUrl.IsDeleted() {
+		return ErrLinkDeleted
+	}
+
+	now := time.Now()
+	shortUrl.DeletedAt = &now
+	if err := uc.linkRepo.Update(ctx, shortUrl, retention); err != nil {
+		return fmt.Errorf("failed to delete short url: %w", err)
+	}
+	return nil
+}
+
+// RestoreShortUrl brings back a soft deleted link within the retention window
+func (uc *shortUrlUseCase) RestoreShortUrl(ctx context.Context, code string) (*dto.GetShortUrlResponse, error) {
+	shortUrl, err := uc.linkRepo.GetByCode(ctx, linkKey(ctx, code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find short url: %w", err)
+	}
+	if err := authorizeLink(ctx, shortUrl); err != nil {
+		return nil, err
+	}
+	if !shortUrl.IsDeleted() {
+		return nil, ErrLinkNotDeleted
+	}
+
+	now := time.Now()
+	if !shortUrl.IsExpired(now) {
+		if err := uc.checkReactivation(ctx); err != nil {
+			return nil, err
+		}
+	}
+	shortUrl.DeletedAt = nil
+	shortUrl.UpdatedAt = &now
+	if err := uc.linkRepo.Update(ctx, shortUrl, uc.storageTTL(shortUrl.ExpiresAt, now)); err != nil {
+		return nil, fmt.Errorf("failed to restore short url: %w", err)
+	}
+	return uc.toGetResponse(shortUrl), nil
+}
+
+// getActiveLink loads a link that is neither soft deleted nor expired
+func (uc *shortUrlUseCase) getActiveLink(ctx context.Context, code string) (*entity.ShortURL, error) {
+	shortUrl, err := uc.getLink(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if shortUrl.IsExpired(time.Now()) {
+		return nil, ErrLinkExpired
+	}
+	return shortUrl, nil
+}
+
+// getLink loads a link that is not soft deleted
+func (uc *shortUrlUseCase) getLink(ctx context.Context, code string) (*entity.ShortURL, error) {
+	shortUrl, err := uc.linkRepo.GetByCode(ctx, linkKey(ctx, code))
+	if errors.Is(err, ErrLinkNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find short url: %w", err)
+	}
+	if shortUrl.IsDeleted() {
+		return nil, ErrLinkDeleted
+	}
+	return shortUrl, nil
+}
+
+// normalizeTags trims, lowercases and de-duplicates tags
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
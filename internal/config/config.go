@@ -2,11 +2,30 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"shorter-rest-api/internal/domain/entity"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// DefaultRedirectPrefix is the route redirects have always been served
+// under. It keeps serving them when REDIRECT_PREFIX changes, so links
+// already handed out stay valid.
+const DefaultRedirectPrefix = "/shortlinks"
+
+// DefaultExhaustedLinkMessage answers visits of a click limited link that
+// served all its redirects when EXHAUSTED_LINK_MESSAGE is empty
+const DefaultExhaustedLinkMessage = "this link has reached its click limit"
+
+// DefaultComingSoonMessage is shown to visits of a link before its
+// activation window when SCHEDULE_COMING_SOON_MESSAGE is empty
+const DefaultComingSoonMessage = "this link is not live yet, please come back later"
+
+// SystemPaths are the first path segments of the routes other than
+// redirects. Neither aliases nor the redirect prefix may use them.
+var SystemPaths = []string{"api", "swagger", "ping", "metrics"}
+
 // Config holds all configuration for the application
 type Config struct {
 	// Redis configuration
@@ -16,13 +35,101 @@ type Config struct {
 		Password string
 	}
 
+	// Storage configuration
+	Storage struct {
+		Driver   string // redis, memory or file
+		FilePath string // Database file used by the file driver
+	}
+
+	// Custom alias configuration
+	Alias struct {
+		Charset       string   // Characters allowed in an alias
+		MinLength     int      // Minimum alias length
+		MaxLength     int      // Maximum alias length
+		ReservedWords []string // Aliases refused besides the system routes
+	}
+
+	// Destination URL configuration
+	URL struct {
+		AllowedSchemes   []string // Schemes destinations may use, lowercase
+		SortQuery        bool     // Sort query parameters so reordered queries share a code
+		RejectShortLinks bool     // Refuse destinations that are our own short links instead of collapsing them
+		MaxChainDepth    int      // Short links a visit may pass through, counting the visited one
+	}
+
+	// Destination policy configuration
+	Policy struct {
+		AllowDomains    []string // Only these domains and their subdomains may be shortened when set
+		DenyDomains     []string // Domains and subdomains that may never be shortened
+		BlocklistPath   string   // Hash-prefix threat blocklist file, empty disables it
+		BlocklistReload int      // How often the blocklist file is checked for changes in seconds
+	}
+
+	// API key authentication configuration
+	Auth struct {
+		Enabled  bool   // Require an API key on the /api routes
+		AdminKey string // Bootstrap admin secret used to issue the first keys
+	}
+
+	// Default quotas of API keys, a key can override them
+	Quota struct {
+		MaxLinks        int // Active links a key may own, 0 means unlimited
+		MaxDailyCreates int // Links a key may create per UTC day, 0 means unlimited
+	}
+
+	// Rate limiting configuration, a zero limit disables the route group
+	RateLimit struct {
+		Enabled        bool
+		CreateLimit    int // Creates per window and client
+		CreateWindow   int // Window of the create limit in seconds
+		RedirectLimit  int // Redirects per window and client
+		RedirectWindow int // Window of the redirect limit in seconds
+		APILimit       int // Other API requests per window and client
+		APIWindow      int // Window of the API limit in seconds
+		PasswordLimit  int // Password guesses per window and link
+		PasswordWindow int // Window of the password limit in seconds
+	}
+
+	// Password protected link configuration
+	Password struct {
+		Secret    string // Key signing the access cookies, random per process when empty
+		AccessTTL int    // How long a visitor who gave the password is let through in seconds
+	}
+
+	// Scheduled link configuration
+	Schedule struct {
+		FallbackURL       string // Destination of visits outside the activation window of links without their own
+		ComingSoonMessage string // Message of the page served before activation when there is no fallback
+	}
+
+	// Click analytics configuration
+	Analytics struct {
+		GeoIPPath       string // MaxMind country database (.mmdb), empty disables country lookup
+		QueueSize       int    // Click events buffered for asynchronous recording
+		Workers         int    // Goroutines writing click events
+		BatchSize       int    // Click events written per batch
+		FlushInterval   int    // Longest wait before a partial batch is written in milliseconds
+		MinuteRetention int    // How long per-minute click counters are kept in seconds
+		Retention       int    // How long per-hour counters and breakdowns are kept in seconds
+		VisitorSalt     string // Secret mixed into the visitor hash, random per process when empty
+	}
+
 	// Server configuration
 	Server struct {
-		Port         string
-		AllowOrigins string
+		Port           string
+		AllowOrigins   string
+		TrustedProxies []string // Proxies whose X-Forwarded-For names the client IP
+		PublicBaseURL  string   // Scheme and host serving the links of the default domain
+		ShortDomains   []string // Branded domains links can also be created on, as scheme and host
+		RedirectPrefix string   // Route prefix of the redirects, empty serves them at the root
+		RedirectStatus int      // Status of the redirects of links that do not choose one
 	}
-	MaximumShortUrlCount int // Maximum number of short URLs
-	Expiration           int // Default expiration time for cache entries in seconds
+	MaximumShortUrlCount int    // Maximum number of active short URLs of the whole service, 0 means unlimited
+	Expiration           int    // Default lifetime of a short URL in seconds, 0 means never expire
+	ExpiredLinkRetention int    // How long expired links are kept to answer 410 Gone in seconds
+	DeletedLinkRetention int    // How long soft deleted links can be restored in seconds, 0 deletes permanently
+	IdempotencyKeyTTL    int    // How long an Idempotency-Key is remembered in seconds
+	ExhaustedLinkMessage string // Message of the 410 Gone answered once a click limited link is used up
 }
 
 // viperInstance is a singleton instance of viper
@@ -59,11 +166,66 @@ func InitConfig() {
 func setDefaults() {
 	// Server defaults
 	viperInstance.SetDefault("server.port", "8080")
+	viperInstance.SetDefault("REDIRECT_PREFIX", DefaultRedirectPrefix)
+	viperInstance.SetDefault("REDIRECT_STATUS", 302)
 
 	// Redis defaults
 	viperInstance.SetDefault("redis.host", "localhost")
 	viperInstance.SetDefault("redis.port", "6379")
 
+	// Storage defaults
+	viperInstance.SetDefault("STORAGE_DRIVER", "redis")
+	viperInstance.SetDefault("STORAGE_FILE_PATH", "shorter.db")
+
+	// Alias defaults
+	viperInstance.SetDefault("ALIAS_CHARSET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_")
+	viperInstance.SetDefault("ALIAS_MIN_LENGTH", 3)
+	viperInstance.SetDefault("ALIAS_MAX_LENGTH", 32)
+
+	// URL defaults
+	viperInstance.SetDefault("URL_ALLOWED_SCHEMES", "http,https")
+	viperInstance.SetDefault("URL_MAX_CHAIN_DEPTH", 3)
+
+	// Policy defaults
+	viperInstance.SetDefault("POLICY_BLOCKLIST_RELOAD", 30)
+
+	// Auth defaults
+	viperInstance.SetDefault("AUTH_ENABLED", true)
+
+	// Rate limit defaults
+	viperInstance.SetDefault("RATE_LIMIT_ENABLED", true)
+	viperInstance.SetDefault("RATE_LIMIT_CREATE", 60)
+	viperInstance.SetDefault("RATE_LIMIT_CREATE_WINDOW", 60)
+	viperInstance.SetDefault("RATE_LIMIT_REDIRECT", 600)
+	viperInstance.SetDefault("RATE_LIMIT_REDIRECT_WINDOW", 60)
+	viperInstance.SetDefault("RATE_LIMIT_API", 300)
+	viperInstance.SetDefault("RATE_LIMIT_API_WINDOW", 60)
+	viperInstance.SetDefault("RATE_LIMIT_PASSWORD", 5)
+	viperInstance.SetDefault("RATE_LIMIT_PASSWORD_WINDOW", 300)
+
+	// Password defaults
+	viperInstance.SetDefault("LINK_PASSWORD_ACCESS_TTL", 3600)
+
+	// Analytics defaults
+	viperInstance.SetDefault("ANALYTICS_QUEUE_SIZE", 10000)
+	viperInstance.SetDefault("ANALYTICS_WORKERS", 2)
+	viperInstance.SetDefault("ANALYTICS_BATCH_SIZE", 200)
+	viperInstance.SetDefault("ANALYTICS_FLUSH_INTERVAL_MS", 500)
+	viperInstance.SetDefault("ANALYTICS_MINUTE_RETENTION", 172800)
+	viperInstance.SetDefault("ANALYTICS_RETENTION", 7776000)
+
+	// Expiration defaults
+	viperInstance.SetDefault("EXPIRED_LINK_RETENTION", 604800)
+	viperInstance.SetDefault("DELETED_LINK_RETENTION", 2592000)
+
+	// Idempotency defaults
+	viperInstance.SetDefault("IDEMPOTENCY_KEY_TTL", 86400)
+
+	// Click limit defaults
+	viperInstance.SetDefault("EXHAUSTED_LINK_MESSAGE", DefaultExhaustedLinkMessage)
+
+	// Schedule defaults
+	viperInstance.SetDefault("SCHEDULE_COMING_SOON_MESSAGE", DefaultComingSoonMessage)
 }
 
 // Load loads the configuration from viper
@@ -79,14 +241,165 @@ func Load() (*Config, error) {
 	config.Redis.Port = viperInstance.GetString("REDIS_PORT")
 	config.Redis.Password = viperInstance.GetString("REDIS_PASSWORD")
 
+	// Storage configuration
+	config.Storage.Driver = viperInstance.GetString("STORAGE_DRIVER")
+	config.Storage.FilePath = viperInstance.GetString("STORAGE_FILE_PATH")
+
+	// Alias configuration
+	config.Alias.Charset = viperInstance.GetString("ALIAS_CHARSET")
+	config.Alias.MinLength = viperInstance.GetInt("ALIAS_MIN_LENGTH")
+	config.Alias.MaxLength = viperInstance.GetInt("ALIAS_MAX_LENGTH")
+	config.Alias.ReservedWords = splitList(viperInstance.GetString("ALIAS_RESERVED_WORDS"))
+
+	// URL configuration
+	for _, scheme := range splitList(viperInstance.GetString("URL_ALLOWED_SCHEMES")) {
+		config.URL.AllowedSchemes = append(config.URL.AllowedSchemes, strings.ToLower(scheme))
+	}
+	config.URL.SortQuery = viperInstance.GetBool("URL_SORT_QUERY")
+	config.URL.RejectShortLinks = viperInstance.GetBool("URL_REJECT_SHORT_LINKS")
+	config.URL.MaxChainDepth = viperInstance.GetInt("URL_MAX_CHAIN_DEPTH")
+	if config.URL.MaxChainDepth < 1 {
+		return nil, fmt.Errorf("invalid URL_MAX_CHAIN_DEPTH %d: must be at least 1", config.URL.MaxChainDepth)
+	}
+
+	// Policy configuration
+	config.Policy.AllowDomains = splitList(viperInstance.GetString("POLICY_ALLOW_DOMAINS"))
+	config.Policy.DenyDomains = splitList(viperInstance.GetString("POLICY_DENY_DOMAINS"))
+	config.Policy.BlocklistPath = viperInstance.GetString("POLICY_BLOCKLIST_PATH")
+	config.Policy.BlocklistReload = viperInstance.GetInt("POLICY_BLOCKLIST_RELOAD")
+
+	// Auth configuration
+	config.Auth.Enabled = viperInstance.GetBool("AUTH_ENABLED")
+	config.Auth.AdminKey = viperInstance.GetString("ADMIN_API_KEY")
+
+	// Quota configuration
+	config.Quota.MaxLinks = viperInstance.GetInt("QUOTA_MAX_LINKS")
+	config.Quota.MaxDailyCreates = viperInstance.GetInt("QUOTA_MAX_DAILY_CREATES")
+
+	// Rate limit configuration
+	config.RateLimit.Enabled = viperInstance.GetBool("RATE_LIMIT_ENABLED")
+	config.RateLimit.CreateLimit = viperInstance.GetInt("RATE_LIMIT_CREATE")
+	config.RateLimit.CreateWindow = viperInstance.GetInt("RATE_LIMIT_CREATE_WINDOW")
+	config.RateLimit.RedirectLimit = viperInstance.GetInt("RATE_LIMIT_REDIRECT")
+	config.RateLimit.RedirectWindow = viperInstance.GetInt("RATE_LIMIT_REDIRECT_WINDOW")
+	config.RateLimit.APILimit = viperInstance.GetInt("RATE_LIMIT_API")
+	config.RateLimit.APIWindow = viperInstance.GetInt("RATE_LIMIT_API_WINDOW")
+	config.RateLimit.PasswordLimit = viperInstance.GetInt("RATE_LIMIT_PASSWORD")
+	config.RateLimit.PasswordWindow = viperInstance.GetInt("RATE_LIMIT_PASSWORD_WINDOW")
+
+	// Password configuration
+	config.Password.Secret = viperInstance.GetString("LINK_PASSWORD_SECRET")
+	config.Password.AccessTTL = viperInstance.GetInt("LINK_PASSWORD_ACCESS_TTL")
+
+	// Analytics configuration
+	config.Analytics.GeoIPPath = viperInstance.GetString("GEOIP_DB_PATH")
+	config.Analytics.QueueSize = viperInstance.GetInt("ANALYTICS_QUEUE_SIZE")
+	config.Analytics.Workers = viperInstance.GetInt("ANALYTICS_WORKERS")
+	config.Analytics.BatchSize = viperInstance.GetInt("ANALYTICS_BATCH_SIZE")
+	config.Analytics.FlushInterval = viperInstance.GetInt("ANALYTICS_FLUSH_INTERVAL_MS")
+	config.Analytics.MinuteRetention = viperInstance.GetInt("ANALYTICS_MINUTE_RETENTION")
+	config.Analytics.Retention = viperInstance.GetInt("ANALYTICS_RETENTION")
+	config.Analytics.VisitorSalt = viperInstance.GetString("ANALYTICS_VISITOR_SALT")
+
 	// Server configuration
 	config.Server.Port = viperInstance.GetString("PORT")
 	config.Server.AllowOrigins = viperInstance.GetString("ALLOW_ORIGINS")
+	config.Server.TrustedProxies = splitList(viperInstance.GetString("TRUSTED_PROXIES"))
+	port := config.Server.Port
+	if port == "" {
+		port = "8080"
+	}
+	baseURL, err := publicURL(viperInstance.GetString("PUBLIC_BASE_URL"), "http://localhost:"+port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PUBLIC_BASE_URL: %w", err)
+	}
+	config.Server.PublicBaseURL = baseURL
+	for _, domain := range splitList(viperInstance.GetString("SHORT_DOMAINS")) {
+		domainURL, err := publicURL(domain, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHORT_DOMAINS entry %q: %w", domain, err)
+		}
+		config.Server.ShortDomains = append(config.Server.ShortDomains, domainURL)
+	}
+	redirectPrefix, err := routePrefix(viperInstance.GetString("REDIRECT_PREFIX"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIRECT_PREFIX: %w", err)
+	}
+	config.Server.RedirectPrefix = redirectPrefix
+	config.Server.RedirectStatus = viperInstance.GetInt("REDIRECT_STATUS")
+	if !entity.IsRedirectStatus(config.Server.RedirectStatus) {
+		return nil, fmt.Errorf("invalid REDIRECT_STATUS %d: must be 301, 302, 307 or 308", config.Server.RedirectStatus)
+	}
 	config.MaximumShortUrlCount = viperInstance.GetInt("MAXIMUM_SHORT_URL_COUNT")
 	config.Expiration = viperInstance.GetInt("EXPIRATION")
+	config.ExpiredLinkRetention = viperInstance.GetInt("EXPIRED_LINK_RETENTION")
+	config.DeletedLinkRetention = viperInstance.GetInt("DELETED_LINK_RETENTION")
+	config.IdempotencyKeyTTL = viperInstance.GetInt("IDEMPOTENCY_KEY_TTL")
+	config.ExhaustedLinkMessage = strings.TrimSpace(viperInstance.GetString("EXHAUSTED_LINK_MESSAGE"))
+	if fallbackURL := strings.TrimSpace(viperInstance.GetString("SCHEDULE_FALLBACK_URL")); fallbackURL != "" {
+		parsed, err := url.Parse(fallbackURL)
+		if err != nil || parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid SCHEDULE_FALLBACK_URL %q: must be an http(s) URL with a host", fallbackURL)
+		}
+		config.Schedule.FallbackURL = fallbackURL
+	}
+	config.Schedule.ComingSoonMessage = strings.TrimSpace(viperInstance.GetString("SCHEDULE_COMING_SOON_MESSAGE"))
 	return config, nil
 }
 
+// splitList splits a comma separated setting into trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// publicURL normalizes a public URL to its lowercase scheme and host,
+// defaulting to fallback when empty. A bare host is served over https.
+func publicURL(value, fallback string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = fallback
+	}
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return "", fmt.Errorf("%q is not an http(s) URL with a host", value)
+	}
+	if strings.Trim(parsed.Path, "/") != "" {
+		return "", fmt.Errorf("%q must not have a path", value)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
+
+// routePrefix normalizes a route prefix to a leading slash without a
+// trailing one, "/" becoming empty for the root
+func routePrefix(value string) (string, error) {
+	prefix := strings.Trim(strings.TrimSpace(value), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(prefix, ":*?#%") || strings.Contains(prefix, "//") {
+		return "", fmt.Errorf("%q must be a plain path", value)
+	}
+	first, _, _ := strings.Cut(prefix, "/")
+	for _, path := range SystemPaths {
+		if strings.EqualFold(first, path) {
+			return "", fmt.Errorf("%q would shadow the /%s routes", value, path)
+		}
+	}
+	return "/" + prefix, nil
+}
+
 // GetViper returns the viper instance
 func GetViper() *viper.Viper {
 	if viperInstance == nil {
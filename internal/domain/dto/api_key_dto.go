@@ -0,0 +1,34 @@
+package dto
+
+// IssueAPIKeyRequest represents the request to issue an API key
+type IssueAPIKeyRequest struct {
+	// Name identifies the team or service using the key
+	Name string `json:"name" binding:"required"`
+	// Admin keys can manage keys and every link
+	Admin bool `json:"admin"`
+	// Quotas of the key, 0 uses the configured default and -1 lifts the limit
+	MaxLinks        int `json:"max_links" binding:"min=-1"`
+	MaxDailyCreates int `json:"max_daily_creates" binding:"min=-1"`
+}
+
+// UpdateQuotaRequest represents the request to change the quotas of an API key
+type UpdateQuotaRequest struct {
+	// Quotas of the key, 0 uses the configured default and -1 lifts the limit
+	MaxLinks        int `json:"max_links" binding:"min=-1"`
+	MaxDailyCreates int `json:"max_daily_creates" binding:"min=-1"`
+}
+
+// APIKeyResponse represents an API key. The secret is only returned when
+// the key is issued or rotated.
+type APIKeyResponse struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Prefix          string `json:"prefix"`
+	Admin           bool   `json:"admin"`
+	MaxLinks        int    `json:"max_links"`
+	MaxDailyCreates int    `json:"max_daily_creates"`
+	CreatedAt       string `json:"created_at"`
+	RotatedAt       string `json:"rotated_at,omitempty"`
+	RevokedAt       string `json:"revoked_at,omitempty"`
+	Secret          string `json:"secret,omitempty"`
+}
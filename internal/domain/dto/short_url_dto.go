@@ -1,17 +1,216 @@
 package dto
 
-// LoginRequest represents the login request payload
+import "time"
+
+// CreateRequest represents the create short URL request payload
 type CreateRequest struct {
 	OriginalUrl string `json:"original_url" binding:"required"`
+	// Title is an optional human-readable label
+	Title string `json:"title"`
+	// Tags group links for filtering
+	Tags []string `json:"tags"`
+	// Alias is an optional human-readable code used instead of a generated one
+	Alias string `json:"alias"`
+	// Domain is the branded domain serving the link, the default domain when empty
+	Domain string `json:"domain"`
+	// RedirectStatus is 301, 302, 307 or 308, 0 uses the server default
+	RedirectStatus int `json:"redirect_status"`
+	// ForwardQuery merges the query of a visit into the destination query
+	ForwardQuery bool `json:"forward_query"`
+	// ForwardPath appends the path following the code to the destination path
+	ForwardPath bool `json:"forward_path"`
+	// QueryConflict is incoming (default) or destination, the side keeping a parameter both queries set
+	QueryConflict string `json:"query_conflict"`
+	// Password protects the link, visitors must give it before being redirected
+	Password string `json:"password"`
+	// MaxClicks is how many redirects the link serves, 1 makes a one-time link, 0 means unlimited
+	MaxClicks int `json:"max_clicks"`
+	// NotBefore and NotAfter bound when the link redirects, RFC 3339 timestamps or dates,
+	// it is live from creation and until it expires when unset
+	NotBefore *string `json:"not_before"`
+	NotAfter  *string `json:"not_after"`
+	// FallbackURL is where visits outside the activation window go, the server default when empty
+	FallbackURL string `json:"fallback_url"`
+	// RoutingRules send matching visits elsewhere than original_url, the first matching rule wins
+	RoutingRules []RoutingRule `json:"routing_rules"`
+	// ForceNew mints a fresh code even if the URL already has a live one
+	ForceNew bool `json:"force_new"`
+	// ExpiresAt sets an absolute expiry, mutually exclusive with TTLSeconds
+	ExpiresAt *time.Time `json:"expires_at"`
+	// TTLSeconds sets the lifetime in seconds, 0 keeps the link forever
+	TTLSeconds *int64 `json:"ttl_seconds"`
+	// IdempotencyKey is taken from the Idempotency-Key header
+	IdempotencyKey string `json:"-" swaggerignore:"true"`
+}
+
+// UpdateRequest represents a partial update of a short URL, nil fields are left unchanged
+type UpdateRequest struct {
+	OriginalUrl *string   `json:"original_url"`
+	Title       *string   `json:"title"`
+	Tags        *[]string `json:"tags"`
+	// RedirectStatus is 301, 302, 307 or 308, 0 goes back to the server default
+	RedirectStatus *int    `json:"redirect_status"`
+	ForwardQuery   *bool   `json:"forward_query"`
+	ForwardPath    *bool   `json:"forward_path"`
+	QueryConflict  *string `json:"query_conflict"`
+	// Password protects the link, an empty password removes the protection
+	Password *string `json:"password"`
+	// MaxClicks is how many redirects the link serves in all, 0 removes the limit
+	MaxClicks *int `json:"max_clicks"`
+	// NotBefore and NotAfter are RFC 3339 timestamps or dates, an empty value removes the bound
+	NotBefore *string `json:"not_before"`
+	NotAfter  *string `json:"not_after"`
+	// FallbackURL is where visits outside the activation window go, empty goes back to the server default
+	FallbackURL *string `json:"fallback_url"`
+	// RoutingRules replaces the rules of the link, an empty list removes them
+	RoutingRules *[]RoutingRule `json:"routing_rules"`
+}
+
+// RoutingRule sends the visits matching all its conditions to destination.
+// A condition left empty matches any visit, a list matches any of its values.
+type RoutingRule struct {
+	// OS lists operating systems: iOS, Android, Windows, macOS, ChromeOS, Linux or Other
+	OS []string `json:"os,omitempty"`
+	// Devices lists device classes: desktop, mobile, tablet or bot
+	Devices []string `json:"devices,omitempty"`
+	// Languages lists language tags such as fr or pt-BR matched against the
+	// preferred language of Accept-Language, fr also matching fr-CA
+	Languages []string `json:"languages,omitempty"`
+	// Countries lists ISO 3166-1 alpha-2 codes looked up from the client address
+	Countries []string `json:"countries,omitempty"`
+	// TimeFrom and TimeTo bound a daily window as HH:MM, time_to excluded,
+	// wrapping past midnight when time_from is later
+	TimeFrom string `json:"time_from,omitempty"`
+	TimeTo   string `json:"time_to,omitempty"`
+	// Timezone is the IANA zone of the daily window, UTC when empty
+	Timezone    string `json:"timezone,omitempty"`
+	Destination string `json:"destination" binding:"required"`
+}
+
+// UpdateExpirationRequest represents the change of a link's lifetime,
+// exactly one of the fields must be set
+type UpdateExpirationRequest struct {
+	// ExpiresAt sets an absolute expiry
+	ExpiresAt *time.Time `json:"expires_at"`
+	// TTLSeconds sets the lifetime from now in seconds, 0 keeps the link forever
+	TTLSeconds *int64 `json:"ttl_seconds"`
 }
 
 type GetShortUrlResponse struct {
-	ID          string `json:"id"`
-	OriginalUrl string `json:"original_url"`
-	CreatedAt   string `json:"created_at"`
+	ID          string   `json:"id"`
+	Domain      string   `json:"domain,omitempty"`
+	ShortUrl    string   `json:"short_url"`
+	OriginalUrl string   `json:"original_url"`
+	Title       string   `json:"title,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	OwnerID     string   `json:"owner_id,omitempty"`
+	// RedirectStatus is the status redirects answer with
+	RedirectStatus int    `json:"redirect_status"`
+	ForwardQuery   bool   `json:"forward_query"`
+	ForwardPath    bool   `json:"forward_path"`
+	QueryConflict  string `json:"query_conflict"`
+	// Template tells the destination has placeholders filled on redirect
+	Template bool `json:"template,omitempty"`
+	// Protected tells visitors must give a password
+	Protected bool `json:"protected,omitempty"`
+	// MaxClicks is how many redirects the link serves, unlimited when 0
+	MaxClicks int `json:"max_clicks,omitempty"`
+	// RemainingClicks is how many redirects a click limited link has left,
+	// only reported when getting a single link
+	RemainingClicks *int `json:"remaining_clicks,omitempty"`
+	// Activation window of the link
+	NotBefore   string `json:"not_before,omitempty"`
+	NotAfter    string `json:"not_after,omitempty"`
+	FallbackURL string `json:"fallback_url,omitempty"`
+	// ScheduleState is scheduled before not_before, ended from not_after on and active otherwise
+	ScheduleState string `json:"schedule_state"`
+	// RoutingRules send matching visits elsewhere than original_url
+	RoutingRules []RoutingRule `json:"routing_rules,omitempty"`
+	// Preview is the destination of the visit described by the preview query
+	Preview string `json:"preview,omitempty"`
+}
+
+// RedirectRequest describes a visit of a short link
+type RedirectRequest struct {
+	// Path follows the code in the visited URL, such as /extra/path
+	Path string
+	// Query is the raw query of the visited URL
+	Query string
+	// Password is given to visit a password protected link
+	Password string
+	// AccessToken is the token of an earlier visit that gave the password
+	AccessToken string
+	// Visitor details routing rules match on
+	UserAgent      string
+	AcceptLanguage string
+	ClientIP       string
+	// Country is the ISO 3166-1 alpha-2 code of the visitor, looked up from
+	// ClientIP when empty. Previews give it directly.
+	Country string
+	// Probe checks where a visit goes without using up a click, as the HEAD
+	// requests of link previewers and uptime checkers do
+	Probe bool
+}
+
+// RedirectResponse tells where a visit is redirected
+type RedirectResponse struct {
+	ID       string
+	Location string
+	Status   int
+	// AccessToken lets later visits skip the password until AccessExpiresAt,
+	// set when the visit gave the password of the link
+	AccessToken     string
+	AccessExpiresAt time.Time
+	// Fallback tells the visit fell outside the activation window of the
+	// link and goes to its fallback URL instead
+	Fallback bool
+	// Routed tells the location depends on who visits and when, so the
+	// redirect must not be cached
+	Routed bool
 }
 
 type CreateResponse struct {
 	ID       string `json:"id"`
+	Domain   string `json:"domain,omitempty"`
 	ShortUrl string `json:"short_url"`
+	// Quota is sent as X-Quota-* headers when a link was created
+	Quota *QuotaStatus `json:"-"`
+}
+
+// ListRequest represents the query of a short URL listing
+type ListRequest struct {
+	// Cursor is the next_cursor of the previous page
+	Cursor string `form:"cursor"`
+	// Limit is the page size, 20 by default and at most 100
+	Limit int `form:"limit"`
+	// Sort is created_at (oldest first) or -created_at (newest first, default)
+	Sort string `form:"sort"`
+	// Host filters by destination host
+	Host string `form:"host"`
+	// Tag filters by tag
+	Tag string `form:"tag"`
+	// CreatedFrom and CreatedTo bound the creation time, as RFC 3339 timestamps or dates
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	// Domain only lists the links of a short link domain
+	Domain string `form:"domain"`
+}
+
+// ListResponse represents a page of short URLs
+type ListResponse struct {
+	Items      []*GetShortUrlResponse `json:"items"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// QuotaStatus reports what is left of the quotas of an API key after a
+// create. A zero limit means the quota does not apply.
+type QuotaStatus struct {
+	LinksLimit            int
+	LinksRemaining        int
+	DailyCreatesLimit     int
+	DailyCreatesRemaining int
+	ResetAt               int64 // Unix time the daily creates reset
 }
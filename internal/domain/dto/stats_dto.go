@@ -0,0 +1,48 @@
+package dto
+
+// ClickRequest carries the request details of a redirect needed for analytics
+type ClickRequest struct {
+	Domain    string // Domain of the link, empty for the default domain
+	ClientIP  string
+	UserAgent string
+	Referrer  string
+}
+
+// StatsRequest represents the query of a click stats request
+type StatsRequest struct {
+	// From and To bound the range, as RFC 3339 timestamps or dates. To
+	// defaults to now and From to one hour, day or 30 days before To.
+	From string `form:"from"`
+	To   string `form:"to"`
+	// Granularity is minute, hour or day (default)
+	Granularity string `form:"granularity"`
+}
+
+// StatsBucket is the number of clicks in one time bucket
+type StatsBucket struct {
+	Start  string `json:"start"`
+	Clicks int64  `json:"clicks"`
+}
+
+// VisitorsBucket is the estimated number of unique visitors in one day
+type VisitorsBucket struct {
+	Start    string `json:"start"`
+	Visitors int64  `json:"visitors"`
+}
+
+// StatsResponse represents the click activity of a short URL
+type StatsResponse struct {
+	ID          string        `json:"id"`
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Granularity string        `json:"granularity"`
+	TotalClicks int64         `json:"total_clicks"`
+	Series      []StatsBucket `json:"series"`
+	// Breakdowns cover the whole days the range touches
+	Referrers map[string]int64 `json:"referrers"`
+	Browsers  map[string]int64 `json:"browsers"`
+	Countries map[string]int64 `json:"countries"`
+	// UniqueVisitors and DailyUniques are HyperLogLog estimates over the same days
+	UniqueVisitors int64            `json:"unique_visitors"`
+	DailyUniques   []VisitorsBucket `json:"daily_uniques"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+)
+
+// APIKey is a credential of a team calling the API. Only a hash of the
+// secret is stored, the secret itself is shown once when issued.
+type APIKey struct {
+	ID        string
+	Name      string
+	Hash      string // Hex SHA-256 of the secret
+	Prefix    string // First characters of the secret, to recognise it in listings
+	Admin     bool   // Admin keys manage keys and every link
+	CreatedAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time // set once the key can no longer be used
+
+	// Quotas of the key, 0 uses the configured default and a negative
+	// value lifts the limit. Admin keys have no quotas.
+	MaxLinks        int
+	MaxDailyCreates int
+}
+
+// IsRevoked reports whether the key was revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
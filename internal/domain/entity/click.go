@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"time"
+)
+
+// Granularity is the width of a click counter bucket
+type Granularity string
+
+// Supported click counter granularities
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+)
+
+// Duration returns the width of a bucket
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityMinute:
+		return time.Minute
+	case GranularityHour:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// BucketStart truncates t to the UTC start of its bucket
+func (g Granularity) BucketStart(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Duration())
+}
+
+// Click breakdown dimensions
+const (
+	DimensionReferrer = "referrer"
+	DimensionBrowser  = "browser"
+	DimensionCountry  = "country"
+)
+
+// ClickEvent represents one redirect of a short URL. The raw request
+// fields are captured on the hot path; the derived fields are filled in
+// asynchronously before the event is stored.
+type ClickEvent struct {
+	Code       string
+	OccurredAt time.Time
+	ClientIP   string
+	UserAgent  string
+	Referrer   string
+
+	ReferrerHost string // "direct" when the click had no referrer
+	Browser      string // User-agent family
+	Country      string // ISO country code or "unknown"
+	VisitorID    string // Salted hash of the client IP and user agent
+}
+
+// Dimensions returns the breakdown values of the event by dimension
+func (e *ClickEvent) Dimensions() map[string]string {
+	return map[string]string{
+		DimensionReferrer: e.ReferrerHost,
+		DimensionBrowser:  e.Browser,
+		DimensionCountry:  e.Country,
+	}
+}
+
+// ClickBucket is the number of clicks in one time bucket
+type ClickBucket struct {
+	Start  time.Time
+	Clicks int64
+}
+
+// VisitorBucket is the estimated number of unique visitors in one day
+type VisitorBucket struct {
+	Start    time.Time
+	Visitors int64
+}
+
+// ClickStats is the click activity of a short URL over a time range
+type ClickStats struct {
+	Series []ClickBucket
+	// Breakdowns holds clicks per value for each dimension, at day resolution
+	Breakdowns map[string]map[string]int64
+	// DailyVisitors estimates the unique visitors of each day the range touches
+	DailyVisitors []VisitorBucket
+	// UniqueVisitors estimates the unique visitors across those days
+	UniqueVisitors int64
+}
+
+// Valid reports whether g is a supported granularity
+func (g Granularity) Valid() bool {
+	return g == GranularityMinute || g == GranularityHour || g == GranularityDay
+}
@@ -1,12 +1,155 @@
 package entity
 
 import (
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 // ShortURL represents the short_urls table
 type ShortURL struct {
 	Code        string
+	Domain      string // Branded domain serving the link, empty for the default domain
 	OriginalURL string
+	Title       string
+	Tags        []string
 	CreatedAt   time.Time
+	UpdatedAt   *time.Time
+	ExpiresAt   *time.Time // nil means the link never expires
+	DeletedAt   *time.Time // set while the link is soft deleted
+	OwnerID     string     // ID of the API key that created the link, empty when anonymous
+	// RedirectStatus is the HTTP status of the redirect, 0 uses the server default
+	RedirectStatus int
+	// Passthrough of the visited URL to the destination
+	ForwardQuery  bool   // Merge the query of the visit into the destination query
+	ForwardPath   bool   // Append the path following the code to the destination path
+	QueryConflict string // Query side keeping a parameter both set, QueryConflictIncoming when empty
+	// PasswordHash is the bcrypt hash of the password visits must give, empty when unprotected
+	PasswordHash string
+	// MaxClicks is how many redirects the link serves before answering 410 Gone, 0 means unlimited
+	MaxClicks int
+	// Activation window, the link only redirects to OriginalURL in between
+	NotBefore   *time.Time // nil means the link is live from its creation
+	NotAfter    *time.Time // nil means the link stays live until it expires
+	FallbackURL string     // Destination outside the window, the server default when empty
+	// RoutingRules send the visits they match elsewhere than OriginalURL, the first matching rule wins
+	RoutingRules []RoutingRule
+}
+
+// RoutingRule sends the visits matching all its conditions to Destination.
+// A condition left empty matches any visit, a list matches any of its values.
+type RoutingRule struct {
+	OS        []string // Operating systems as the user agent parser names them, such as iOS
+	Devices   []string // Device classes: desktop, mobile, tablet or bot
+	Languages []string // Lowercase language tags matched against the preferred language of the visit
+	Countries []string // ISO 3166-1 alpha-2 codes of the country of the client address
+	// Daily window as HH:MM, TimeTo excluded. The window wraps past midnight
+	// when TimeFrom is after TimeTo.
+	TimeFrom    string
+	TimeTo      string
+	Timezone    string // IANA zone of the daily window, UTC when empty
+	Destination string
+}
+
+// Values of ShortURL.QueryConflict
+const (
+	QueryConflictIncoming    = "incoming"    // The visit overrides the destination parameter
+	QueryConflictDestination = "destination" // The destination parameter is kept
+)
+
+// Schedule states of a link, see ShortURL.ScheduleState
+const (
+	ScheduleScheduled = "scheduled" // Before NotBefore
+	ScheduleActive    = "active"    // Within the activation window
+	ScheduleEnded     = "ended"     // From NotAfter on
+)
+
+// LinkKey identifies the link with code on domain. Codes are unique per
+// domain, links on the default domain are keyed by their bare code.
+func LinkKey(domain, code string) string {
+	if domain == "" {
+		return code
+	}
+	return domain + "/" + code
+}
+
+// Key returns the storage key of the link, see LinkKey
+func (s *ShortURL) Key() string {
+	return LinkKey(s.Domain, s.Code)
+}
+
+// OriginKey identifies the destination of the links of an API key on
+// domain, so that every domain and every key reuses its own link for a
+// destination. Owner IDs are marked with @, which no domain starts with.
+func OriginKey(domain, ownerID, originalURL string) string {
+	key := originalURL
+	if ownerID != "" {
+		key = "@" + ownerID + " " + key
+	}
+	if domain != "" {
+		key = domain + " " + key
+	}
+	return key
+}
+
+// IsRedirectStatus reports whether status is a redirect a link may use:
+// 301 or 308 for permanent links, 302 or 307 for temporary ones, where
+// 307 and 308 keep the method and body of the request
+func IsRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// IsDeleted reports whether the link is soft deleted
+func (s *ShortURL) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
+
+// IsExpired reports whether the link has expired at now
+func (s *ShortURL) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// IsActive reports whether the link redirects at now: neither soft deleted nor expired
+func (s *ShortURL) IsActive(now time.Time) bool {
+	return !s.IsDeleted() && !s.IsExpired(now)
+}
+
+// HasSchedule reports whether the link has an activation window
+func (s *ShortURL) HasSchedule() bool {
+	return s.NotBefore != nil || s.NotAfter != nil
+}
+
+// ScheduleState tells where now falls in the activation window of the link
+func (s *ShortURL) ScheduleState(now time.Time) string {
+	if s.NotBefore != nil && now.Before(*s.NotBefore) {
+		return ScheduleScheduled
+	}
+	if s.NotAfter != nil && !now.Before(*s.NotAfter) {
+		return ScheduleEnded
+	}
+	return ScheduleActive
+}
+
+// Host returns the lowercase host name of the destination URL
+func (s *ShortURL) Host() string {
+	parsed, err := url.Parse(s.OriginalURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// HasTag reports whether the link is tagged with tag
+func (s *ShortURL) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
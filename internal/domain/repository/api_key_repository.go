@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"shorter-rest-api/internal/domain/entity"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches an ID or hash
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository stores API keys, looked up by ID or by secret hash
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *entity.APIKey) error
+	// UpdateAPIKey overwrites an existing key, moving its hash lookup when
+	// the hash changed
+	UpdateAPIKey(ctx context.Context, key *entity.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	// ListAPIKeys returns every key, revoked ones included, oldest first
+	ListAPIKeys(ctx context.Context) ([]*entity.APIKey, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRepository remembers which code an Idempotency-Key produced so
+// retried create requests never mint a second code
+type IdempotencyRepository interface {
+	// ClaimIdempotencyKey atomically claims an unused key. When the key was
+	// already claimed it returns claimed=false and the code recorded for it,
+	// which is empty while the first request is still in flight. The claim
+	// lapses after ttl unless completed.
+	ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (code string, claimed bool, err error)
+	// CompleteIdempotencyKey records the code created for a claimed key and
+	// keeps it for ttl
+	CompleteIdempotencyKey(ctx context.Context, key, code string, ttl time.Duration) error
+	// ReleaseIdempotencyKey drops a claim whose request failed
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
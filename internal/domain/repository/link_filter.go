@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"shorter-rest-api/internal/domain/entity"
+	"time"
+)
+
+// LinkCursor is the position of a link in the listing order, which sorts
+// by creation time in milliseconds and then by link key
+type LinkCursor struct {
+	CreatedAt int64  // Unix milliseconds
+	Code      string // Link key, see entity.LinkKey
+}
+
+// CursorOf returns the listing position of link
+func CursorOf(link *entity.ShortURL) LinkCursor {
+	return LinkCursor{CreatedAt: link.CreatedAt.UnixMilli(), Code: link.Key()}
+}
+
+// LinkFilter narrows and pages a listing of short URLs. Soft deleted
+// links are never listed.
+type LinkFilter struct {
+	Host        string     // Lowercase destination host
+	Tag         string     // Lowercase tag
+	OwnerID     string     // API key ID owning the links
+	Domain      *string    // Short link domain, empty for the default domain, nil for every domain
+	CreatedFrom *time.Time // Inclusive lower bound of CreatedAt
+	CreatedTo   *time.Time // Inclusive upper bound of CreatedAt
+	Descending  bool       // Newest first
+	After       *LinkCursor
+	Limit       int
+}
+
+// Matches reports whether link passes the filter, ignoring the cursor
+func (f LinkFilter) Matches(link *entity.ShortURL) bool {
+	if link.IsDeleted() {
+		return false
+	}
+	if f.Host != "" && link.Host() != f.Host {
+		return false
+	}
+	if f.Tag != "" && !link.HasTag(f.Tag) {
+		return false
+	}
+	if f.OwnerID != "" && link.OwnerID != f.OwnerID {
+		return false
+	}
+	if f.Domain != nil && link.Domain != *f.Domain {
+		return false
+	}
+	created := link.CreatedAt.UnixMilli()
+	if f.CreatedFrom != nil && created < f.CreatedFrom.UnixMilli() {
+		return false
+	}
+	if f.CreatedTo != nil && created > f.CreatedTo.UnixMilli() {
+		return false
+	}
+	return true
+}
+
+// IsAfterCursor reports whether position comes after the filter cursor
+// in the requested order
+func (f LinkFilter) IsAfterCursor(position LinkCursor) bool {
+	if f.After == nil {
+		return true
+	}
+	return f.Less(*f.After, position)
+}
+
+// Less reports whether a is listed before b in the requested order
+func (f LinkFilter) Less(a, b LinkCursor) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return (a.CreatedAt < b.CreatedAt) != f.Descending
+	}
+	if a.Code == b.Code {
+		return false
+	}
+	return (a.Code < b.Code) != f.Descending
+}
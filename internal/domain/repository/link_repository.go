@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"shorter-rest-api/internal/domain/entity"
+	"time"
+)
+
+var (
+	// ErrLinkNotFound is returned when no short URL is stored for a code
+	ErrLinkNotFound = errors.New("short url not found")
+	// ErrCodeAlreadyExists is returned when a code is already reserved
+	ErrCodeAlreadyExists = errors.New("short code already exists")
+	// ErrTotalLinkLimit is returned when a create would go over LinkLimits.Total
+	ErrTotalLinkLimit = errors.New("active link limit reached")
+	// ErrOwnerLinkLimit is returned when a create would go over LinkLimits.Owner
+	ErrOwnerLinkLimit = errors.New("active link limit of the owner reached")
+)
+
+// LinkLimits caps the active links a create may bring the store to, a
+// limit of 0 is not enforced
+type LinkLimits struct {
+	Total int // active links of the whole store
+	Owner int // active links of the owner of the created link
+}
+
+// LinkRepository defines the storage contract for short URLs. Links are
+// stored under their key (see entity.LinkKey), which is what the code
+// parameters and the reverse entries hold, so that codes are unique per
+// domain.
+type LinkRepository interface {
+	// Create atomically reserves link.Key() and stores the short URL together
+	// with the OriginalURL -> code reverse entry of link.OwnerID on
+	// link.Domain, which is only claimed when no other live link holds it.
+	// It returns ErrCodeAlreadyExists without writing anything when the
+	// code is taken, and ErrTotalLinkLimit or ErrOwnerLinkLimit when an
+	// active link would go over limits, checked in the same atomic step so
+	// that concurrent creates cannot exceed them.
+	// A ttl of zero or less keeps the records forever.
+	Create(ctx context.Context, link *entity.ShortURL, ttl time.Duration, limits LinkLimits) error
+	// Update replaces an existing short URL and resets the ttl of its
+	// records. The OriginalURL -> code reverse entry follows the link: it
+	// moves when the destination changes, is dropped while the link is
+	// soft deleted and is claimed again when free. It returns
+	// ErrLinkNotFound when the code is not stored.
+	Update(ctx context.Context, link *entity.ShortURL, ttl time.Duration) error
+	// Delete permanently removes the short URL, its reverse entry and its
+	// click stats
+	Delete(ctx context.Context, code string) error
+	GetByCode(ctx context.Context, code string) (*entity.ShortURL, error)
+	// GetByOriginalURL follows the reverse index to the live short URL of
+	// the API key ownerID currently assigned to originalURL on domain, an
+	// empty ownerID standing for links created without a key
+	GetByOriginalURL(ctx context.Context, domain, ownerID, originalURL string) (*entity.ShortURL, error)
+	// List returns up to filter.Limit links matching the filter that come
+	// after filter.After in the requested order
+	List(ctx context.Context, filter LinkFilter) ([]*entity.ShortURL, error)
+	// Count counts the active links, neither soft deleted nor expired
+	Count(ctx context.Context) (int, error)
+	// CountByOwner counts the active links of an API key
+	CountByOwner(ctx context.Context, ownerID string) (int, error)
+	// ConsumeClick counts one redirect of the link under code unless limit
+	// redirects were already counted, atomically so that concurrent visits
+	// can never exceed the limit. It returns the redirects counted once the
+	// call is done and whether this one was counted. The count lives and
+	// dies with the link, updates keep it.
+	ConsumeClick(ctx context.Context, code string, limit int) (int, bool, error)
+	// ConsumedClicks returns the redirects ConsumeClick counted for code
+	ConsumedClicks(ctx context.Context, code string) (int, error)
+	Close() error
+}
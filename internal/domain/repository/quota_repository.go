@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// QuotaRepository keeps the usage counters behind the per API key quotas
+type QuotaRepository interface {
+	// ReserveDailyCreate counts one create of owner on the UTC day of day
+	// unless limit creates were already counted. It returns the creates
+	// counted that day once the call is done and whether one was reserved.
+	ReserveDailyCreate(ctx context.Context, ownerID string, day time.Time, limit int) (int, bool, error)
+	// ReleaseDailyCreate gives back a reservation of a create that failed
+	ReleaseDailyCreate(ctx context.Context, ownerID string, day time.Time) error
+}
+
+// QuotaDay formats the UTC day a daily counter belongs to
+func QuotaDay(day time.Time) string {
+	return day.UTC().Format("20060102")
+}
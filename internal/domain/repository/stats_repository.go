@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+	"time"
+)
+
+// StatsQuery selects the click activity of one link. From and To are
+// bucket aligned and inclusive.
+type StatsQuery struct {
+	Code        string
+	From        time.Time
+	To          time.Time
+	Granularity entity.Granularity
+}
+
+// StatsRepository stores time-bucketed click counters
+type StatsRepository interface {
+	// RecordClicks increments the minute, hour and day buckets of each event
+	// and its per-day breakdown counters, and adds its visitor to the
+	// per-day unique visitor estimate. A batch is written in one round trip.
+	RecordClicks(ctx context.Context, events []*entity.ClickEvent) error
+	// GetClickStats returns the buckets of the query range, including empty
+	// ones, and the breakdowns and unique visitors of the days the range touches
+	GetClickStats(ctx context.Context, query StatsQuery) (*entity.ClickStats, error)
+}
+
+// Days returns the UTC day starts the query range touches
+func (q StatsQuery) Days() []time.Time {
+	var days []time.Time
+	last := entity.GranularityDay.BucketStart(q.To)
+	for day := entity.GranularityDay.BucketStart(q.From); !day.After(last); day = day.Add(24 * time.Hour) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// Series lays the clicks counted per bucket start (unix seconds) out over
+// every bucket of the query range
+func (q StatsQuery) Series(counts map[int64]int64) []entity.ClickBucket {
+	var series []entity.ClickBucket
+	step := q.Granularity.Duration()
+	last := q.Granularity.BucketStart(q.To)
+	for start := q.Granularity.BucketStart(q.From); !start.After(last); start = start.Add(step) {
+		series = append(series, entity.ClickBucket{Start: start, Clicks: counts[start.Unix()]})
+	}
+	return series
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// UnknownCountry is reported when the country of an address cannot be told
+const UnknownCountry = "unknown"
+
+// GeoLocator resolves client addresses to countries
+type GeoLocator interface {
+	// Country returns the ISO 3166-1 alpha-2 code of ip or UnknownCountry
+	Country(ip string) string
+	Close() error
+}
+
+// maxMindLocator reads a local MaxMind (GeoLite2/GeoIP2) country or city database
+type maxMindLocator struct {
+	reader *maxminddb.Reader
+}
+
+// noopLocator is used when no database is configured
+type noopLocator struct{}
+
+// NewGeoLocator opens the database at path. An empty path disables country
+// lookup so deployments without a database still record clicks.
+func NewGeoLocator(path string) (GeoLocator, error) {
+	if path == "" {
+		return noopLocator{}, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &maxMindLocator{reader: reader}, nil
+}
+
+// Country looks the address up in the database
+func (l *maxMindLocator) Country(ip string) string {
+	address := net.ParseIP(ip)
+	if address == nil {
+		return UnknownCountry
+	}
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := l.reader.Lookup(address, &record); err != nil || record.Country.ISOCode == "" {
+		return UnknownCountry
+	}
+	return strings.ToUpper(record.Country.ISOCode)
+}
+
+// Close unmaps the database file
+func (l *maxMindLocator) Close() error {
+	return l.reader.Close()
+}
+
+func (noopLocator) Country(string) string {
+	return UnknownCountry
+}
+
+func (noopLocator) Close() error {
+	return nil
+}
@@ -0,0 +1,227 @@
+package analytics
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DirectReferrer is reported for clicks without a usable Referer header
+const DirectReferrer = "direct"
+
+// dropLogInterval limits how often dropped events are logged
+const dropLogInterval = 10 * time.Second
+
+// RecorderOptions tunes the click event pipeline
+type RecorderOptions struct {
+	QueueSize     int           // Events buffered before new ones are dropped
+	Workers       int           // Goroutines enriching and writing batches
+	BatchSize     int           // A worker flushes once it holds this many events
+	FlushInterval time.Duration // ... or when its oldest event waited this long
+	Salt          string        // Secret mixed into the visitor hash
+}
+
+// RecorderMetrics are the counters of the click event pipeline
+type RecorderMetrics struct {
+	Enqueued   uint64 `json:"enqueued"`    // Events accepted into the queue
+	Dropped    uint64 `json:"dropped"`     // Events rejected because the queue was full
+	Recorded   uint64 `json:"recorded"`    // Events written to storage
+	Failed     uint64 `json:"failed"`      // Events lost to failed writes
+	Batches    uint64 `json:"batches"`     // Batches flushed
+	QueueDepth int    `json:"queue_depth"` // Events waiting in the queue
+	QueueSize  int    `json:"queue_size"`
+}
+
+// Recorder stores click events in the background so redirects never wait
+// on analytics. Events go through a bounded queue to a pool of workers that
+// enrich them with the referrer host, browser family, country and a salted
+// visitor hash, so the client address is never stored, and write them in
+// batches. When storage falls behind and the queue fills up, new events
+// are dropped and counted rather than slowing redirects down.
+type Recorder struct {
+	stats   repository.StatsRepository
+	geo     GeoLocator
+	salt    []byte
+	options RecorderOptions
+
+	// mu guards closed so no event is sent on the closed queue
+	mu     sync.RWMutex
+	closed bool
+	events chan *entity.ClickEvent
+	done   chan struct{}
+
+	enqueued, dropped, recorded, failed, batches atomic.Uint64
+	lastDropLog                                  atomic.Int64
+}
+
+// NewRecorder starts the workers of a recorder. Without a salt a random one
+// is used, so unique visitors only add up within the lifetime of the process.
+func NewRecorder(stats repository.StatsRepository, geo GeoLocator, options RecorderOptions) *Recorder {
+	options.QueueSize = max(options.QueueSize, 1)
+	options.Workers = max(options.Workers, 1)
+	options.BatchSize = max(options.BatchSize, 1)
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	recorder := &Recorder{
+		stats:   stats,
+		geo:     geo,
+		salt:    []byte(options.Salt),
+		options: options,
+		events:  make(chan *entity.ClickEvent, options.QueueSize),
+		done:    make(chan struct{}),
+	}
+	if options.Salt == "" {
+		recorder.salt = make([]byte, 32)
+		rand.Read(recorder.salt)
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < options.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			recorder.work()
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(recorder.done)
+	}()
+	return recorder
+}
+
+// Record queues an event without blocking. It reports false when the event
+// was dropped because the queue is full or the recorder is closed.
+func (r *Recorder) Record(event *entity.ClickEvent) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.closed {
+		select {
+		case r.events <- event:
+			r.enqueued.Add(1)
+			return true
+		default:
+		}
+	}
+
+	dropped := r.dropped.Add(1)
+	now := time.Now().UnixNano()
+	if last := r.lastDropLog.Load(); now-last >= int64(dropLogInterval) && r.lastDropLog.CompareAndSwap(last, now) {
+		log.Printf("click event queue full, %d events dropped so far", dropped)
+	}
+	return false
+}
+
+// Metrics returns a snapshot of the pipeline counters
+func (r *Recorder) Metrics() RecorderMetrics {
+	return RecorderMetrics{
+		Enqueued:   r.enqueued.Load(),
+		Dropped:    r.dropped.Load(),
+		Recorded:   r.recorded.Load(),
+		Failed:     r.failed.Load(),
+		Batches:    r.batches.Load(),
+		QueueDepth: len(r.events),
+		QueueSize:  r.options.QueueSize,
+	}
+}
+
+// Close stops accepting events and waits until the workers flushed every
+// queued event, or until ctx is done
+func (r *Recorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("click events still queued: %w", ctx.Err())
+	}
+}
+
+// work batches events until the queue is closed, flushing when the batch
+// is full or the flush interval elapsed since its first event
+func (r *Recorder) work() {
+	batch := make([]*entity.ClickEvent, 0, r.options.BatchSize)
+	timer := time.NewTimer(r.options.FlushInterval)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				timer.Stop()
+				r.flush(batch)
+				return
+			}
+			r.enrich(event)
+			batch = append(batch, event)
+			if len(batch) == 1 {
+				timer.Reset(r.options.FlushInterval)
+			}
+			if len(batch) < r.options.BatchSize {
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
+		}
+		r.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes a batch in one storage round trip
+func (r *Recorder) flush(batch []*entity.ClickEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	r.batches.Add(1)
+	if err := r.stats.RecordClicks(context.Background(), batch); err != nil {
+		r.failed.Add(uint64(len(batch)))
+		log.Printf("failed to record %d click events: %v", len(batch), err)
+		return
+	}
+	r.recorded.Add(uint64(len(batch)))
+}
+
+// enrich fills in the derived fields of an event
+func (r *Recorder) enrich(event *entity.ClickEvent) {
+	event.ReferrerHost = referrerHost(event.Referrer)
+	event.Browser = ParseUserAgent(event.UserAgent).Family
+	event.Country = r.geo.Country(event.ClientIP)
+	event.VisitorID = r.visitorID(event.ClientIP, event.UserAgent)
+}
+
+// visitorID hashes the client address and user agent with the salt
+func (r *Recorder) visitorID(clientIP, userAgent string) string {
+	hasher := sha256.New()
+	hasher.Write(r.salt)
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(clientIP))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(userAgent))
+	return hex.EncodeToString(hasher.Sum(nil)[:16])
+}
+
+// referrerHost reduces a Referer header to its lowercase host
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(strings.TrimSpace(referrer))
+	if err != nil || parsed.Hostname() == "" {
+		return DirectReferrer
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
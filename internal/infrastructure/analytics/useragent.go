@@ -0,0 +1,109 @@
+package analytics
+
+import (
+	"strings"
+)
+
+// Device classes reported by ParseUserAgent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// UserAgent is the coarse classification of a User-Agent header
+type UserAgent struct {
+	Family string // Browser family, e.g. Chrome
+	OS     string // Operating system, e.g. iOS
+	Device string // desktop, mobile, tablet or bot
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "go-http-client", "headless"}
+
+// browserMarkers is checked in order: most browsers also claim to be Safari
+// or Chrome, so the specific ones come first
+var browserMarkers = []struct {
+	marker string
+	family string
+}{
+	{"edg", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser", "Samsung Internet"},
+	{"yabrowser", "Yandex"},
+	{"firefox/", "Firefox"},
+	{"fxios", "Firefox"},
+	{"crios", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"chromium", "Chrome"},
+	{"safari/", "Safari"},
+	{"msie", "Internet Explorer"},
+	{"trident/", "Internet Explorer"},
+}
+
+var osMarkers = []struct {
+	marker string
+	os     string
+}{
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// OperatingSystem returns the name ParseUserAgent reports for the
+// operating system name, matched case-insensitively, and whether it is one
+func OperatingSystem(name string) (string, bool) {
+	if strings.EqualFold(name, "Other") {
+		return "Other", true
+	}
+	for _, os := range osMarkers {
+		if strings.EqualFold(name, os.os) {
+			return os.os, true
+		}
+	}
+	return "", false
+}
+
+// ParseUserAgent classifies a User-Agent header. Unknown values are
+// reported as "Other" so they still group together in the stats.
+func ParseUserAgent(header string) UserAgent {
+	ua := strings.ToLower(header)
+	result := UserAgent{Family: "Other", OS: "Other", Device: DeviceDesktop}
+	if ua == "" {
+		return result
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return UserAgent{Family: "Bot", OS: "Other", Device: DeviceBot}
+		}
+	}
+	for _, browser := range browserMarkers {
+		if strings.Contains(ua, browser.marker) {
+			result.Family = browser.family
+			break
+		}
+	}
+	for _, os := range osMarkers {
+		if strings.Contains(ua, os.marker) {
+			result.OS = os.os
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		result.Device = DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		result.Device = DeviceMobile
+	}
+	return result
+}
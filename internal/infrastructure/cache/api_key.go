@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	apiKeyKeyPrefix     = "api_keys:"
+	apiKeyHashKeyPrefix = "api_key_hashes:"
+	apiKeyIndexKey      = "api_key_index"
+)
+
+// createAPIKeyScript stores a new key with its hash lookup and listing entry
+//
+// KEYS[1] key record, KEYS[2] hash lookup, KEYS[3] listing index
+// ARGV[1] key payload, ARGV[2] id, ARGV[3] listing score
+var createAPIKeyScript = redis.NewScript(3, `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+redis.call("ZADD", KEYS[3], ARGV[3], ARGV[2])
+return 1
+`)
+
+// updateAPIKeyScript overwrites a key and moves its hash lookup
+//
+// KEYS[1] key record, KEYS[2] hash lookup of the new version
+// ARGV[1] key payload, ARGV[2] id, ARGV[3] hash lookup prefix
+var updateAPIKeyScript = redis.NewScript(2, `
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+local previousKey = ARGV[3] .. cjson.decode(current)["Hash"]
+if previousKey ~= KEYS[2] then
+	redis.call("DEL", previousKey)
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+return 1
+`)
+
+// CreateAPIKey stores a new API key
+func (r *RedisClient) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	rawData, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	conn := r.Conn.Get()
+	defer conn.Close()
+	created, err := redis.Int(createAPIKeyScript.Do(conn, apiKeyKeyPrefix+key.ID, apiKeyHashKeyPrefix+key.Hash, apiKeyIndexKey,
+		rawData, key.ID, key.CreatedAt.UnixMilli()))
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	if created == 0 {
+		return fmt.Errorf("api key %s already exists", key.ID)
+	}
+	return nil
+}
+
+// UpdateAPIKey overwrites an existing API key
+func (r *RedisClient) UpdateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	rawData, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	conn := r.Conn.Get()
+	defer conn.Close()
+	updated, err := redis.Int(updateAPIKeyScript.Do(conn, apiKeyKeyPrefix+key.ID, apiKeyHashKeyPrefix+key.Hash,
+		rawData, key.ID, apiKeyHashKeyPrefix))
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	if updated == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// GetAPIKey gets an API key by ID
+func (r *RedisClient) GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	rawData, err := redis.Bytes(conn.Do("GET", apiKeyKeyPrefix+id))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	var key entity.APIKey
+	if err := json.Unmarshal(rawData, &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash follows the hash lookup to an API key
+func (r *RedisClient) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	conn := r.Conn.Get()
+	id, err := redis.String(conn.Do("GET", apiKeyHashKeyPrefix+hash))
+	conn.Close()
+	if errors.Is(err, redis.ErrNil) {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return r.GetAPIKey(ctx, id)
+}
+
+// ListAPIKeys returns every API key in creation order
+func (r *RedisClient) ListAPIKeys(ctx context.Context) ([]*entity.APIKey, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	ids, err := redis.Strings(conn.Do("ZRANGE", apiKeyIndexKey, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = apiKeyKeyPrefix + id
+	}
+	values, err := redis.ByteSlices(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	keys := make([]*entity.APIKey, 0, len(values))
+	for _, rawData := range values {
+		if rawData == nil {
+			continue
+		}
+		var key entity.APIKey
+		if err := json.Unmarshal(rawData, &key); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, nil
+}
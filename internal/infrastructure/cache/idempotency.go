@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const idempotencyKeyPrefix = "idempotency_keys:"
+
+func idempotencyKey(key string) string {
+	return idempotencyKeyPrefix + key
+}
+
+// ClaimIdempotencyKey claims the key with SET NX, returning the recorded code when already claimed
+func (r *RedisClient) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	// An empty value marks a claim whose request is still in flight
+	reply, err := conn.Do("SET", idempotencyKey(key), "", "NX", "EX", int(ttl/time.Second))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if reply != nil {
+		return "", true, nil
+	}
+
+	code, err := redis.String(conn.Do("GET", idempotencyKey(key)))
+	if errors.Is(err, redis.ErrNil) {
+		// The previous claim was released in between, report it as in flight
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return code, false, nil
+}
+
+// CompleteIdempotencyKey records the code created for the key
+func (r *RedisClient) CompleteIdempotencyKey(ctx context.Context, key, code string, ttl time.Duration) error {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	if _, err := conn.Do("SET", idempotencyKey(key), code, "EX", int(ttl/time.Second)); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes the claim on the key
+func (r *RedisClient) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	if _, err := conn.Do("DEL", idempotencyKey(key)); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
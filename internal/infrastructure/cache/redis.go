@@ -1,114 +1,453 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"shorter-rest-api/internal/config"
 	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
-type IRedisCache interface {
-	Set(key string, value entity.ShortURL, expiration int) error
-	CountKeysByPattern(pattern string) (int, error)
-	Get(key string) (*entity.ShortURL, error)
-	Exists(key string) (bool, error)
-}
+const (
+	shortUrlKeyPrefix  = "short_urls:"
+	originUrlKeyPrefix = "short_url_origins:"
+)
 
 // RedisClient represents a Redis client
 type RedisClient struct {
 	Conn *redis.Pool
+	// MinuteRetention and StatsRetention bound how long per-minute and
+	// per-hour click counters are kept, 0 meaning forever
+	MinuteRetention time.Duration
+	StatsRetention  time.Duration
+}
+
+func shortUrlKey(code string) string {
+	return shortUrlKeyPrefix + code
+}
+
+// originUrlKey returns the reverse key of a destination of an owner on a domain
+func originUrlKey(domain, ownerID, originalURL string) string {
+	return originUrlKeyPrefix + entity.OriginKey(domain, ownerID, originalURL)
+}
+
+// ttlSeconds converts a ttl to whole seconds, 0 meaning the key never
+// expires. Positive sub-second ttls are rounded up so they still expire.
+func ttlSeconds(ttl time.Duration) int {
+	if ttl <= 0 {
+		return 0
+	}
+	return int((ttl + time.Second - 1) / time.Second)
 }
 
-func (r *RedisClient) CountKeysByPattern(pattern string) (int, error) {
-	var count int
-	var cursor uint64 = 0
+// scriptArgs lays out the arguments of a script with a variable number of
+// keys: the key count, the keys and then the other arguments
+func scriptArgs(keys []string, args ...interface{}) []interface{} {
+	scriptArgs := make([]interface{}, 0, 1+len(keys)+len(args))
+	scriptArgs = append(scriptArgs, len(keys))
+	for _, key := range keys {
+		scriptArgs = append(scriptArgs, key)
+	}
+	return append(scriptArgs, args...)
+}
+
+// The scripts below declare every key they touch in KEYS. The keys of the
+// stored version of a link, and the click counters listed for it, are read
+// before running a script; the script only applies them while the stored
+// payload and the counters set are still the ones read, and otherwise
+// returns scriptStale (-9 in the scripts) so that the caller reads them
+// again. Concurrent writes can therefore never make a script act on the
+// keys of a version that was already replaced.
+const (
+	scriptStale = -9
+
+	// scriptAttempts bounds how often a script is run again after the link
+	// changed under it
+	scriptAttempts = 10
+)
 
+// errConcurrentWrites is returned when a link kept changing between
+// reading it and running a script
+var errConcurrentWrites = errors.New("link changed by concurrent writes")
+
+// createScript reserves the code with SET NX semantics and writes the
+// reverse index and listing indexes in the same atomic step, so concurrent
+// creates on any replica can never share a code or leave a half-written link.
+// The reverse entry is only claimed when free, a link created next to the
+// one a destination already has leaves it in place. An active link is only
+// created while its usage sets, trimmed of expired members, are under
+// their limits. A new link under the code of an expired one starts without
+// its consumed clicks and click counters.
+//
+// KEYS[1] link key, KEYS[2] reverse key, KEYS[3] consumed clicks counter,
+// KEYS[4] click counters set, KEYS[5..] usage keys, then the click counters
+// listed in the set when read, followed by the listing index keys
+// ARGV[1] link payload, ARGV[2] link key, ARGV[3] ttl in seconds (0 = never),
+// ARGV[4] listing index score, ARGV[5] number of usage keys,
+// ARGV[6] usage score ("" when the link is not active), ARGV[7] and
+// ARGV[8] limits of the usage keys (0 = unlimited), ARGV[9] current time
+// in Unix milliseconds, ARGV[10] number of click counters
+//
+// Returns 1 when created, 0 when the code is taken, -1 and -2 when the
+// first or second usage key is at its limit, scriptStale when the click
+// counters changed since they were read.
+var createScript = redis.NewScript(-1, `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local usage = tonumber(ARGV[5])
+local counters = tonumber(ARGV[10])
+if redis.call("SCARD", KEYS[4]) ~= counters then
+	return -9
+end
+for i = 5 + usage, 4 + usage + counters do
+	if redis.call("SISMEMBER", KEYS[4], KEYS[i]) == 0 then
+		return -9
+	end
+end
+if ARGV[6] ~= "" then
+	for i = 1, usage do
+		local limit = tonumber(ARGV[6 + i])
+		if limit > 0 then
+			redis.call("ZREMRANGEBYSCORE", KEYS[4 + i], "-inf", ARGV[9])
+			if redis.call("ZCARD", KEYS[4 + i]) >= limit then
+				return -i
+			end
+		end
+	end
+end
+redis.call("DEL", KEYS[3], KEYS[4])
+for i = 5 + usage, 4 + usage + counters do
+	redis.call("DEL", KEYS[i])
+end
+local ttl = tonumber(ARGV[3])
+local owner = redis.call("GET", KEYS[2])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "EX", ttl)
+	if not owner or owner == ARGV[2] then
+		redis.call("SET", KEYS[2], ARGV[2], "EX", ttl)
+	end
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+	if not owner or owner == ARGV[2] then
+		redis.call("SET", KEYS[2], ARGV[2])
+	end
+end
+if ARGV[6] ~= "" then
+	for i = 5, 4 + usage do
+		redis.call("ZADD", KEYS[i], ARGV[6], ARGV[2])
+	end
+end
+for i = 5 + usage + counters, #KEYS do
+	redis.call("ZADD", KEYS[i], ARGV[4], ARGV[2])
+end
+return 1
+`)
+
+// Create atomically reserves the code and stores the short URL with its reverse index
+func (r *RedisClient) Create(ctx context.Context, link *entity.ShortURL, ttl time.Duration, limits repository.LinkLimits) error {
 	conn := r.Conn.Get()
 	defer conn.Close()
-	for {
-		keys, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+
+	// Marshal the value to JSON
+	rawData, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	code := link.Key()
+	usage := usageKeys(link)
+	for attempt := 0; attempt < scriptAttempts; attempt++ {
+		counters, err := redis.Strings(conn.Do("SMEMBERS", countersKey(code)))
 		if err != nil {
-			return 0, err
+			return fmt.Errorf("failed to save short url: %w", err)
 		}
-
-		if len(keys) != 2 {
-			return 0, fmt.Errorf("invalid SCAN response")
+		keys := []string{shortUrlKey(code), originUrlKey(link.Domain, link.OwnerID, link.OriginalURL), consumedClicksKey(code), countersKey(code)}
+		keys = append(keys, usage...)
+		keys = append(keys, counters...)
+		keys = append(keys, indexKeys(link)...)
+		now := time.Now()
+		args := scriptArgs(keys, rawData, code, ttlSeconds(ttl), indexScore(link), len(usage), usageScore(link, now), limits.Total, limits.Owner,
+			now.UnixMilli(), len(counters))
+		created, err := redis.Int(createScript.Do(conn, args...))
+		if err != nil {
+			return fmt.Errorf("failed to save short url: %w", err)
 		}
-
-		// Extract cursor and keys
-		cursor, _ = keys[0].(uint64)
-		keySlice, _ := redis.Strings(keys[1], nil)
-		count += len(keySlice)
-
-		// If cursor is 0, we've scanned all keys
-		if cursor == 0 {
-			break
+		switch created {
+		case scriptStale:
+			continue
+		case 0:
+			return repository.ErrCodeAlreadyExists
+		case -1:
+			return repository.ErrTotalLinkLimit
+		case -2:
+			return repository.ErrOwnerLinkLimit
 		}
+		return nil
 	}
-
-	return count, nil
+	return fmt.Errorf("failed to save short url: %w", errConcurrentWrites)
 }
 
-// Set sets a key-value pair in Redis with expiration
-func (r *RedisClient) Set(key string, value entity.ShortURL, expiration int) error {
-	conn := r.Conn.Get()
+// updateScript overwrites an existing link and keeps its reverse entry
+// consistent: the entry of the previous destination is dropped, and the
+// entry of the current destination points at the link unless the link is
+// soft deleted or the entry belongs to another live link.
+//
+// The listing index memberships of the stored version are replaced by
+// those of the new version, and the usage entries follow whether the link
+// is active.
+//
+// KEYS[1] link key, KEYS[2] reverse key, KEYS[3] reverse key of the stored
+// version, KEYS[4] consumed clicks counter, KEYS[5..] usage keys, then the
+// keys to leave (stored version index keys and the usage key of a previous
+// owner) followed by the listing index keys of the new version
+// ARGV[1] link payload, ARGV[2] link key, ARGV[3] ttl in seconds (0 = never),
+// ARGV[4] stored payload the keys to leave were derived from, ARGV[5] 1
+// when the link owns its reverse entry, ARGV[6] number of keys to leave,
+// ARGV[7] listing index score, ARGV[8] number of usage keys, ARGV[9] usage
+// score ("" when the link is not active)
+//
+// Returns 1 when updated, 0 when the link is missing, scriptStale when the
+// stored payload changed since it was read.
+var updateScript = redis.NewScript(-1, `
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[4] then
+	return -9
+end
+local ttl = tonumber(ARGV[3])
+if KEYS[3] ~= KEYS[2] and redis.call("GET", KEYS[3]) == ARGV[2] then
+	redis.call("DEL", KEYS[3])
+end
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "EX", ttl)
+	if redis.call("EXISTS", KEYS[4]) == 1 then
+		redis.call("EXPIRE", KEYS[4], ttl)
+	end
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+	redis.call("PERSIST", KEYS[4])
+end
+local owner = redis.call("GET", KEYS[2])
+if ARGV[5] ~= "1" then
+	if owner == ARGV[2] then
+		redis.call("DEL", KEYS[2])
+	end
+elseif not owner or owner == ARGV[2] then
+	if ttl > 0 then
+		redis.call("SET", KEYS[2], ARGV[2], "EX", ttl)
+	else
+		redis.call("SET", KEYS[2], ARGV[2])
+	end
+end
+local usage = tonumber(ARGV[8])
+for i = 5, 4 + usage do
+	if ARGV[9] ~= "" then
+		redis.call("ZADD", KEYS[i], ARGV[9], ARGV[2])
+	else
+		redis.call("ZREM", KEYS[i], ARGV[2])
+	end
+end
+local stale = tonumber(ARGV[6])
+for i = 5 + usage, 4 + usage + stale do
+	redis.call("ZREM", KEYS[i], ARGV[2])
+end
+for i = 5 + usage + stale, #KEYS do
+	redis.call("ZADD", KEYS[i], ARGV[7], ARGV[2])
+end
+return 1
+`)
 
-	defer conn.Close()
-	// Marshal the value to JSON
-	rawData, err := json.Marshal(value)
+// deleteScript removes a link, the reverse entry pointing at it, its
+// consumed clicks counter, its click counters and its listing index and
+// usage memberships
+//
+// KEYS[1] link key, KEYS[2] reverse key of the stored version, KEYS[3]
+// consumed clicks counter, KEYS[4] click counters set, KEYS[5..] listing
+// index and usage keys of the stored version, followed by the click
+// counters listed in the set when read
+// ARGV[1] link key, ARGV[2] stored payload the keys were derived from,
+// ARGV[3] number of listing index and usage keys
+//
+// Returns 1 when deleted, 0 when the link is missing, scriptStale when the
+// stored payload or the click counters changed since they were read.
+var deleteScript = redis.NewScript(-1, `
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[2] then
+	return -9
+end
+local memberships = tonumber(ARGV[3])
+if redis.call("SCARD", KEYS[4]) ~= #KEYS - 4 - memberships then
+	return -9
+end
+for i = 5 + memberships, #KEYS do
+	if redis.call("SISMEMBER", KEYS[4], KEYS[i]) == 0 then
+		return -9
+	end
+end
+if redis.call("GET", KEYS[2]) == ARGV[1] then
+	redis.call("DEL", KEYS[2])
+end
+for i = 5, 4 + memberships do
+	redis.call("ZREM", KEYS[i], ARGV[1])
+end
+for i = 5 + memberships, #KEYS do
+	redis.call("DEL", KEYS[i])
+end
+redis.call("DEL", KEYS[1], KEYS[3], KEYS[4])
+return 1
+`)
+
+// Update overwrites an existing short URL, its expiration and reverse entry
+func (r *RedisClient) Update(ctx context.Context, link *entity.ShortURL, ttl time.Duration) error {
+	rawData, err := json.Marshal(link)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
-	// If no expiration is set, use 0
-	// which means the key will not expire
-	return conn.Send("SET", key, rawData, "EX", expiration)
+
+	conn := r.Conn.Get()
+	defer conn.Close()
+	ownsReverse := 0
+	if !link.IsDeleted() {
+		ownsReverse = 1
+	}
+	code := link.Key()
+	usage := usageKeys(link)
+	for attempt := 0; attempt < scriptAttempts; attempt++ {
+		// The stored version tells which listing index memberships to drop
+		stored, rawStored, err := getLink(conn, code)
+		if err != nil {
+			return err
+		}
+		staleKeys := indexKeys(stored)
+		if stored.OwnerID != link.OwnerID && stored.OwnerID != "" {
+			staleKeys = append(staleKeys, ownerUsageKeyPrefix+stored.OwnerID)
+		}
+		keys := []string{shortUrlKey(code), originUrlKey(link.Domain, link.OwnerID, link.OriginalURL), originUrlKey(stored.Domain, stored.OwnerID, stored.OriginalURL),
+			consumedClicksKey(code)}
+		keys = append(keys, usage...)
+		keys = append(keys, staleKeys...)
+		keys = append(keys, indexKeys(link)...)
+		args := scriptArgs(keys, rawData, code, ttlSeconds(ttl), rawStored, ownsReverse, len(staleKeys), indexScore(link), len(usage), usageScore(link, time.Now()))
+		updated, err := redis.Int(updateScript.Do(conn, args...))
+		if err != nil {
+			return fmt.Errorf("failed to update short url: %w", err)
+		}
+		switch updated {
+		case scriptStale:
+			continue
+		case 0:
+			return repository.ErrLinkNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to update short url: %w", errConcurrentWrites)
 }
 
-// Get gets a value from Redis by key
-func (r *RedisClient) Get(key string) (*entity.ShortURL, error) {
+// Delete removes a short URL, its reverse entry, click stats and listing index memberships
+func (r *RedisClient) Delete(ctx context.Context, code string) error {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	for attempt := 0; attempt < scriptAttempts; attempt++ {
+		stored, rawStored, err := getLink(conn, code)
+		if err != nil {
+			return err
+		}
+		counters, err := redis.Strings(conn.Do("SMEMBERS", countersKey(code)))
+		if err != nil {
+			return fmt.Errorf("failed to delete short url: %w", err)
+		}
+		memberships := append(indexKeys(stored), usageKeys(stored)...)
+		keys := []string{shortUrlKey(code), originUrlKey(stored.Domain, stored.OwnerID, stored.OriginalURL), consumedClicksKey(code), countersKey(code)}
+		keys = append(keys, memberships...)
+		keys = append(keys, counters...)
+		deleted, err := redis.Int(deleteScript.Do(conn, scriptArgs(keys, code, rawStored, len(memberships))...))
+		if err != nil {
+			return fmt.Errorf("failed to delete short url: %w", err)
+		}
+		switch deleted {
+		case scriptStale:
+			continue
+		case 0:
+			return repository.ErrLinkNotFound
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to delete short url: %w", errConcurrentWrites)
+}
 
+// GetByCode gets a short URL from Redis by code
+func (r *RedisClient) GetByCode(ctx context.Context, code string) (*entity.ShortURL, error) {
 	conn := r.Conn.Get()
 	defer conn.Close()
 
-	rawData, err := redis.Bytes(conn.Do("GET", fmt.Sprintf("short_urls:%s", key)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get value from Redis: %w", err)
+	shortUrl, _, err := getLink(conn, code)
+	return shortUrl, err
+}
+
+// getLink reads the link under code with the payload it is stored as
+func getLink(conn redis.Conn, code string) (*entity.ShortURL, []byte, error) {
+	rawData, err := redis.Bytes(conn.Do("GET", shortUrlKey(code)))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, nil, repository.ErrLinkNotFound
 	}
-	if len(rawData) <= 0 {
-		return nil, nil
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get value from Redis: %w", err)
 	}
 	var shortUrl entity.ShortURL
 	if err := json.Unmarshal(rawData, &shortUrl); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal value: %w", err)
 	}
-
-	return &shortUrl, nil
+	return &shortUrl, rawData, nil
 }
 
-func (r *RedisClient) Exists(key string) (bool, error) {
+// GetByOriginalURL follows the reverse index to the short URL of owner for the original URL on domain
+func (r *RedisClient) GetByOriginalURL(ctx context.Context, domain, ownerID, originalURL string) (*entity.ShortURL, error) {
 	conn := r.Conn.Get()
-	defer conn.Close()
-	exists, err := redis.Bool(conn.Do("EXISTS", key))
+	code, err := redis.String(conn.Do("GET", originUrlKey(domain, ownerID, originalURL)))
+	conn.Close()
+	if errors.Is(err, redis.ErrNil) {
+		return nil, repository.ErrLinkNotFound
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to check if key exists: %w", err)
+		return nil, fmt.Errorf("failed to get value from Redis: %w", err)
 	}
-	defer conn.Close()
 
-	return exists, nil
+	return r.GetByCode(ctx, code)
 }
 
-// NewRedisClient creates a new Redis client
-func NewRedisClient(cfg *config.Config) (IRedisCache, error) {
+// Close releases the pooled connections
+func (r *RedisClient) Close() error {
+	return r.Conn.Close()
+}
+
+// NewRedisPool creates a Redis connection pool from the configuration
+func NewRedisPool(cfg *config.Config) *redis.Pool {
 	addr := fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port)
-	redisPool := &redis.Pool{
+	return &redis.Pool{
 		MaxIdle:     10,
 		IdleTimeout: 240 * time.Second,
 		Dial: func() (redis.Conn, error) {
 			return redis.Dial("tcp", addr, redis.DialPassword(cfg.Redis.Password))
 		},
 	}
-	return &RedisClient{Conn: redisPool}, nil
+}
+
+// NewRedisClient creates a new Redis client
+func NewRedisClient(cfg *config.Config) (*RedisClient, error) {
+	return &RedisClient{
+		Conn:            NewRedisPool(cfg),
+		MinuteRetention: time.Duration(cfg.Analytics.MinuteRetention) * time.Second,
+		StatsRetention:  time.Duration(cfg.Analytics.Retention) * time.Second,
+	}, nil
 }
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/domain/repository"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// The redirects of click limited links are counted next to the link key,
+// expiring with it. Create and delete drop the counter and update moves
+// its expiry along with the link's.
+const consumedClicksKeyPrefix = "short_url_consumed_clicks:"
+
+func consumedClicksKey(code string) string {
+	return consumedClicksKeyPrefix + code
+}
+
+// consumeClickScript counts one redirect of a live link unless the limit
+// is reached
+//
+// KEYS[1] link key, KEYS[2] consumed clicks counter
+// ARGV[1] limit
+var consumeClickScript = redis.NewScript(2, `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {0, -1}
+end
+local used = tonumber(redis.call("GET", KEYS[2]) or "0")
+if used >= tonumber(ARGV[1]) then
+	return {used, 0}
+end
+used = redis.call("INCR", KEYS[2])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return {used, 1}
+`)
+
+// ConsumeClick counts one redirect of the link under code unless limit is reached
+func (r *RedisClient) ConsumeClick(ctx context.Context, code string, limit int) (int, bool, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	values, err := redis.Int64s(consumeClickScript.Do(conn, shortUrlKey(code), consumedClicksKey(code), limit))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to consume click: %w", err)
+	}
+	if values[1] < 0 {
+		return 0, false, repository.ErrLinkNotFound
+	}
+	return int(values[0]), values[1] == 1, nil
+}
+
+// ConsumedClicks returns the redirects counted against the click limit of code
+func (r *RedisClient) ConsumedClicks(ctx context.Context, code string) (int, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	used, err := redis.Int(conn.Do("GET", consumedClicksKey(code)))
+	if errors.Is(err, redis.ErrNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consumed clicks: %w", err)
+	}
+	return used, nil
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	createdIndexKey     = "short_url_index:created"
+	hostIndexKeyPrefix  = "short_url_index:host:"
+	tagIndexKeyPrefix   = "short_url_index:tag:"
+	ownerIndexKeyPrefix = "short_url_index:owner:"
+
+	// listBatchSize is how many index members are read per round trip
+	listBatchSize = 100
+)
+
+// indexKeys returns the sorted sets listing the link, all scored by
+// creation time so each of them can serve a paged, date ranged listing
+func indexKeys(link *entity.ShortURL) []string {
+	keys := []string{createdIndexKey}
+	if host := link.Host(); host != "" {
+		keys = append(keys, hostIndexKeyPrefix+host)
+	}
+	for _, tag := range link.Tags {
+		keys = append(keys, tagIndexKeyPrefix+tag)
+	}
+	if link.OwnerID != "" {
+		keys = append(keys, ownerIndexKeyPrefix+link.OwnerID)
+	}
+	return keys
+}
+
+func indexScore(link *entity.ShortURL) int64 {
+	return link.CreatedAt.UnixMilli()
+}
+
+// List pages through the most selective index for the filter. Index
+// members only nominate candidates: every link is re-checked against the
+// filter, and members whose link has expired away are cleaned up.
+func (r *RedisClient) List(ctx context.Context, filter repository.LinkFilter) ([]*entity.ShortURL, error) {
+	key := createdIndexKey
+	switch {
+	case filter.Tag != "":
+		key = tagIndexKeyPrefix + filter.Tag
+	case filter.Host != "":
+		key = hostIndexKeyPrefix + filter.Host
+	case filter.OwnerID != "":
+		key = ownerIndexKeyPrefix + filter.OwnerID
+	}
+
+	// Narrow the score range with the date range and the cursor
+	min, max := "-inf", "+inf"
+	minScore, maxScore := int64(0), int64(0)
+	if filter.CreatedFrom != nil {
+		minScore = filter.CreatedFrom.UnixMilli()
+		min = strconv.FormatInt(minScore, 10)
+	}
+	if filter.CreatedTo != nil {
+		maxScore = filter.CreatedTo.UnixMilli()
+		max = strconv.FormatInt(maxScore, 10)
+	}
+	if filter.After != nil {
+		if filter.Descending && (filter.CreatedTo == nil || filter.After.CreatedAt < maxScore) {
+			max = strconv.FormatInt(filter.After.CreatedAt, 10)
+		}
+		if !filter.Descending && (filter.CreatedFrom == nil || filter.After.CreatedAt > minScore) {
+			min = strconv.FormatInt(filter.After.CreatedAt, 10)
+		}
+	}
+
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	var links []*entity.ShortURL
+	var staleCodes []interface{}
+	for offset := 0; len(links) < filter.Limit; offset += listBatchSize {
+		var reply []interface{}
+		var err error
+		if filter.Descending {
+			reply, err = redis.Values(conn.Do("ZREVRANGEBYSCORE", key, max, min, "WITHSCORES", "LIMIT", offset, listBatchSize))
+		} else {
+			reply, err = redis.Values(conn.Do("ZRANGEBYSCORE", key, min, max, "WITHSCORES", "LIMIT", offset, listBatchSize))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read listing index: %w", err)
+		}
+
+		var codes []string
+		var linkKeys []interface{}
+		for i := 0; i+1 < len(reply); i += 2 {
+			code, _ := redis.String(reply[i], nil)
+			score, _ := redis.Int64(reply[i+1], nil)
+			if filter.IsAfterCursor(repository.LinkCursor{CreatedAt: score, Code: code}) {
+				codes = append(codes, code)
+				linkKeys = append(linkKeys, shortUrlKey(code))
+			}
+		}
+
+		if len(linkKeys) > 0 {
+			values, err := redis.ByteSlices(conn.Do("MGET", linkKeys...))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get values from Redis: %w", err)
+			}
+			for i, rawData := range values {
+				if rawData == nil {
+					staleCodes = append(staleCodes, codes[i])
+					continue
+				}
+				var link entity.ShortURL
+				if err := json.Unmarshal(rawData, &link); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+				}
+				if filter.Matches(&link) && len(links) < filter.Limit {
+					links = append(links, &link)
+				}
+			}
+		}
+
+		if len(reply)/2 < listBatchSize {
+			break
+		}
+	}
+
+	// Clean up after paging so removals do not shift the offsets
+	if len(staleCodes) > 0 {
+		if _, err := conn.Do("ZREM", append([]interface{}{key}, staleCodes...)...); err != nil {
+			return nil, fmt.Errorf("failed to clean listing index: %w", err)
+		}
+	}
+	return links, nil
+}
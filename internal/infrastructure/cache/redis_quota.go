@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	dailyCreatesKeyPrefix = "quota_daily_creates:"
+	// dailyCreatesRetention keeps a day counter a little past its day so
+	// clock skew between instances cannot restart it
+	dailyCreatesRetention = 48 * time.Hour
+)
+
+// reserveDailyCreateScript counts one create unless the limit is reached
+//
+// KEYS[1] day counter
+// ARGV[1] limit, ARGV[2] counter retention in seconds
+var reserveDailyCreateScript = redis.NewScript(1, `
+local used = redis.call("INCR", KEYS[1])
+if used == 1 then
+	redis.call("EXPIRE", KEYS[1], ARGV[2])
+end
+if used > tonumber(ARGV[1]) then
+	redis.call("DECR", KEYS[1])
+	return {used - 1, 0}
+end
+return {used, 1}
+`)
+
+// releaseDailyCreateScript gives back one create without going below zero
+//
+// KEYS[1] day counter
+var releaseDailyCreateScript = redis.NewScript(1, `
+if tonumber(redis.call("GET", KEYS[1]) or "0") > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 1
+`)
+
+func dailyCreatesKey(ownerID string, day time.Time) string {
+	return dailyCreatesKeyPrefix + ownerID + ":" + repository.QuotaDay(day)
+}
+
+// ReserveDailyCreate counts one create of owner for the day unless limit is reached
+func (r *RedisClient) ReserveDailyCreate(ctx context.Context, ownerID string, day time.Time, limit int) (int, bool, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	values, err := redis.Int64s(reserveDailyCreateScript.Do(conn, dailyCreatesKey(ownerID, day), limit, int64(dailyCreatesRetention/time.Second)))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to reserve daily create: %w", err)
+	}
+	return int(values[0]), values[1] == 1, nil
+}
+
+// ReleaseDailyCreate gives back a create reserved for the day
+func (r *RedisClient) ReleaseDailyCreate(ctx context.Context, ownerID string, day time.Time) error {
+	conn := r.Conn.Get()
+	defer conn.Close()
+	if _, err := releaseDailyCreateScript.Do(conn, dailyCreatesKey(ownerID, day)); err != nil {
+		return fmt.Errorf("failed to release daily create: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	clicksKeyPrefix = "clicks:"
+	visitorsKeyName = "visitors"
+	countersKeyName = "counters"
+)
+
+// Click counters are hashes: minute and hour buckets and the breakdowns are
+// split per UTC day so old days expire on their own, day buckets live in a
+// single hash per link.
+//
+//	clicks:<code>:minute:<YYYYMMDD>     minute start -> clicks
+//	clicks:<code>:hour:<YYYYMMDD>       hour start -> clicks
+//	clicks:<code>:day                   day start -> clicks
+//	clicks:<code>:<dimension>:<YYYYMMDD> value -> clicks
+//
+// Unique visitors are HyperLogLogs per UTC day, so counting the union of
+// several days is a single PFCOUNT.
+//
+//	clicks:<code>:visitors:<YYYYMMDD>
+//
+// Every counter of a link is listed in a set, so that the counters can be
+// dropped together with the link without scanning the keyspace.
+//
+//	clicks:<code>:counters
+func clicksKey(code, name string, day time.Time) string {
+	if day.IsZero() {
+		return clicksKeyPrefix + code + ":" + name
+	}
+	return clicksKeyPrefix + code + ":" + name + ":" + day.Format("20060102")
+}
+
+// countersKey returns the set listing the counters of a link
+func countersKey(code string) string {
+	return clicksKey(code, countersKeyName, time.Time{})
+}
+
+// bucketKey returns the hash holding the buckets of granularity on day
+func bucketKey(code string, granularity entity.Granularity, day time.Time) string {
+	if granularity == entity.GranularityDay {
+		return clicksKey(code, string(granularity), time.Time{})
+	}
+	return clicksKey(code, string(granularity), day)
+}
+
+// RecordClicks increments the counters of a batch of clicks in one
+// pipelined transaction. Each touched key is given its retention once and
+// listed in the counters set of its link.
+func (r *RedisClient) RecordClicks(ctx context.Context, events []*entity.ClickEvent) error {
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+	retentions := make(map[string]time.Duration)
+	counters := make(map[string][]interface{})
+	for _, event := range events {
+		r.sendClick(conn, event, retentions, counters)
+	}
+	for code, keys := range counters {
+		conn.Send("SADD", append([]interface{}{countersKey(code)}, keys...)...)
+		retentions[countersKey(code)] = r.StatsRetention
+	}
+	for key, retention := range retentions {
+		if seconds := ttlSeconds(retention); seconds > 0 {
+			conn.Send("EXPIRE", key, seconds)
+		}
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+	return nil
+}
+
+// sendClick queues the commands counting one click on conn, notes the
+// retention of the keys it touches and adds the keys seen for the first
+// time to the counters of the link
+func (r *RedisClient) sendClick(conn redis.Conn, event *entity.ClickEvent, retentions map[string]time.Duration, counters map[string][]interface{}) {
+	touch := func(key string, retention time.Duration) {
+		if _, ok := retentions[key]; !ok {
+			counters[event.Code] = append(counters[event.Code], key)
+		}
+		retentions[key] = retention
+	}
+	day := entity.GranularityDay.BucketStart(event.OccurredAt)
+	for _, granularity := range []entity.Granularity{entity.GranularityMinute, entity.GranularityHour, entity.GranularityDay} {
+		key := bucketKey(event.Code, granularity, day)
+		conn.Send("HINCRBY", key, granularity.BucketStart(event.OccurredAt).Unix(), 1)
+		if granularity == entity.GranularityMinute {
+			touch(key, r.MinuteRetention)
+		} else {
+			touch(key, r.StatsRetention)
+		}
+	}
+	for dimension, value := range event.Dimensions() {
+		key := clicksKey(event.Code, dimension, day)
+		conn.Send("HINCRBY", key, value, 1)
+		touch(key, r.StatsRetention)
+	}
+	if event.VisitorID != "" {
+		key := clicksKey(event.Code, visitorsKeyName, day)
+		conn.Send("PFADD", key, event.VisitorID)
+		touch(key, r.StatsRetention)
+	}
+}
+
+// GetClickStats reads the counters of the query range in one round trip
+func (r *RedisClient) GetClickStats(ctx context.Context, query repository.StatsQuery) (*entity.ClickStats, error) {
+	days := query.Days()
+	var bucketKeys []string
+	if query.Granularity == entity.GranularityDay {
+		bucketKeys = []string{bucketKey(query.Code, query.Granularity, time.Time{})}
+	} else {
+		for _, day := range days {
+			bucketKeys = append(bucketKeys, bucketKey(query.Code, query.Granularity, day))
+		}
+	}
+	dimensions := []string{entity.DimensionReferrer, entity.DimensionBrowser, entity.DimensionCountry}
+
+	conn := r.Conn.Get()
+	defer conn.Close()
+	for _, key := range bucketKeys {
+		conn.Send("HGETALL", key)
+	}
+	for _, dimension := range dimensions {
+		for _, day := range days {
+			conn.Send("HGETALL", clicksKey(query.Code, dimension, day))
+		}
+	}
+	visitorKeys := make([]interface{}, 0, len(days))
+	for _, day := range days {
+		key := clicksKey(query.Code, visitorsKeyName, day)
+		conn.Send("PFCOUNT", key)
+		visitorKeys = append(visitorKeys, key)
+	}
+	conn.Send("PFCOUNT", visitorKeys...)
+	if err := conn.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+
+	counts := make(map[int64]int64)
+	for range bucketKeys {
+		buckets, err := redis.Int64Map(conn.Receive())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get click stats: %w", err)
+		}
+		for start, clicks := range buckets {
+			unix, err := strconv.ParseInt(start, 10, 64)
+			if err != nil {
+				continue
+			}
+			counts[unix] += clicks
+		}
+	}
+	stats := &entity.ClickStats{Series: query.Series(counts), Breakdowns: make(map[string]map[string]int64)}
+	for _, dimension := range dimensions {
+		breakdown := make(map[string]int64)
+		for range days {
+			values, err := redis.Int64Map(conn.Receive())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get click stats: %w", err)
+			}
+			for value, clicks := range values {
+				breakdown[value] += clicks
+			}
+		}
+		stats.Breakdowns[dimension] = breakdown
+	}
+	for _, day := range days {
+		visitors, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get click stats: %w", err)
+		}
+		stats.DailyVisitors = append(stats.DailyVisitors, entity.VisitorBucket{Start: day, Visitors: visitors})
+	}
+	uniqueVisitors, err := redis.Int64(conn.Receive())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+	stats.UniqueVisitors = uniqueVisitors
+	return stats, nil
+}
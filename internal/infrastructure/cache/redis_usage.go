@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Active links are counted with sorted sets, one for all links and one per
+// owner, scored by the expiry of each link in Unix milliseconds. Links
+// leave the sets when they are deleted or soft deleted; expired members
+// are trimmed when counting, so a count is a trim plus a ZCARD instead of
+// a scan over every key.
+const (
+	usageKey            = "short_url_usage"
+	ownerUsageKeyPrefix = "short_url_usage:owner:"
+)
+
+// usageKeys returns the usage sets counting the link
+func usageKeys(link *entity.ShortURL) []string {
+	keys := []string{usageKey}
+	if link.OwnerID != "" {
+		keys = append(keys, ownerUsageKeyPrefix+link.OwnerID)
+	}
+	return keys
+}
+
+// usageScore returns the usage set score of the link, empty when the link
+// is not active and must not be counted
+func usageScore(link *entity.ShortURL, now time.Time) string {
+	if !link.IsActive(now) {
+		return ""
+	}
+	if link.ExpiresAt == nil {
+		return "+inf"
+	}
+	return strconv.FormatInt(link.ExpiresAt.UnixMilli(), 10)
+}
+
+// Count counts the active short URLs
+func (r *RedisClient) Count(ctx context.Context) (int, error) {
+	return r.countUsage(usageKey)
+}
+
+// CountByOwner counts the active short URLs of an API key
+func (r *RedisClient) CountByOwner(ctx context.Context, ownerID string) (int, error) {
+	return r.countUsage(ownerUsageKeyPrefix + ownerID)
+}
+
+// countUsage trims the expired members of a usage set and counts the rest
+func (r *RedisClient) countUsage(key string) (int, error) {
+	conn := r.Conn.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("ZREMRANGEBYSCORE", key, "-inf", time.Now().UnixMilli())
+	conn.Send("ZCARD", key)
+	reply, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count short urls: %w", err)
+	}
+	return redis.Int(reply[1], nil)
+}
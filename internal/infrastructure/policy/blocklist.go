@@ -0,0 +1,189 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultThreat is reported for blocklist entries that name no threat
+const DefaultThreat = "blocklisted"
+
+// Hash prefixes are 4 to 32 bytes long, as in Safe Browsing
+const (
+	minPrefixBytes = 4
+	maxPrefixBytes = 32
+)
+
+// blocklist holds the SHA-256 hash prefixes of blocked URL expressions,
+// grouped by prefix length, with the threat each one is listed for
+type blocklist struct {
+	prefixes map[int]map[string]string
+}
+
+// blocklistFile is a blocklist loaded from a file. Each line holds the hex
+// encoded hash prefix of a URL expression, such as the hash of
+// "evil.example/" or "example.com/phish/login.html", optionally followed by
+// the threat it is listed for. Blank lines and lines starting with # are
+// skipped.
+type blocklistFile struct {
+	path    string
+	current atomic.Pointer[blocklist]
+
+	// mu serializes reloads, modTime and size tell when the file changed
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// reload parses the file and swaps it in, keeping the entries in use when
+// the file cannot be read or parsed
+func (f *blocklistFile) reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	list, count, err := readBlocklist(f.path)
+	if err != nil {
+		return err
+	}
+	f.current.Store(list)
+	f.modTime, f.size = info.ModTime(), info.Size()
+	log.Printf("loaded %d blocklist entries from %s", count, f.path)
+	return nil
+}
+
+// watch reloads the file whenever its modification time or size changes
+// until stop is closed
+func (f *blocklistFile) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			log.Printf("failed to check blocklist %s: %v", f.path, err)
+			continue
+		}
+		f.mu.Lock()
+		changed := !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+		f.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if err := f.reload(); err != nil {
+			log.Printf("keeping the previous blocklist: %v", err)
+		}
+	}
+}
+
+// lookup returns the threat destination is listed for, empty when it is not
+func (f *blocklistFile) lookup(destination *url.URL) string {
+	list := f.current.Load()
+	for _, expression := range urlExpressions(destination) {
+		hash := sha256.Sum256([]byte(expression))
+		for length, prefixes := range list.prefixes {
+			if threat, ok := prefixes[string(hash[:length])]; ok {
+				return threat
+			}
+		}
+	}
+	return ""
+}
+
+// readBlocklist parses a blocklist file, failing on the first bad line
+func readBlocklist(path string) (*blocklist, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	defer file.Close()
+
+	list := &blocklist{prefixes: make(map[int]map[string]string)}
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		prefix, err := hex.DecodeString(fields[0])
+		if err != nil || len(prefix) < minPrefixBytes || len(prefix) > maxPrefixBytes {
+			return nil, 0, fmt.Errorf("blocklist %s line %d: %q is not a hex hash prefix of %d to %d bytes", path, line, fields[0], minPrefixBytes, maxPrefixBytes)
+		}
+		threat := DefaultThreat
+		if len(fields) > 1 {
+			threat = strings.Join(fields[1:], " ")
+		}
+		if list.prefixes[len(prefix)] == nil {
+			list.prefixes[len(prefix)] = make(map[string]string)
+		}
+		list.prefixes[len(prefix)][string(prefix)] = threat
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	return list, count, nil
+}
+
+// urlExpressions returns the host suffix and path prefix combinations a
+// URL is looked up by, as in Safe Browsing: the exact host and up to four
+// suffixes of its last five components, combined with the exact path with
+// and without query, the root and up to three more leading directories.
+// For http://a.b.example/1/2.html?p=1 they include
+// "a.b.example/1/2.html?p=1", "b.example/1/" and "b.example/", while a
+// top-level domain alone is never looked up.
+func urlExpressions(destination *url.URL) []string {
+	host := strings.TrimSuffix(destination.Hostname(), ".")
+	hosts := []string{host}
+	if net.ParseIP(host) == nil {
+		components := strings.Split(host, ".")
+		for n := min(5, len(components)-1); n >= 2; n-- {
+			hosts = append(hosts, strings.Join(components[len(components)-n:], "."))
+		}
+	}
+
+	path := destination.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	var paths []string
+	if destination.RawQuery != "" {
+		paths = append(paths, path+"?"+destination.RawQuery)
+	}
+	paths = append(paths, path, "/")
+	directories := strings.Split(strings.Trim(path, "/"), "/")
+	prefix := "/"
+	for i := 0; i < len(directories)-1 && i < 3; i++ {
+		prefix += directories[i] + "/"
+		paths = append(paths, prefix)
+	}
+
+	seen := make(map[string]bool, len(hosts)*len(paths))
+	expressions := make([]string, 0, len(hosts)*len(paths))
+	for _, host := range hosts {
+		for _, path := range paths {
+			if expression := host + path; !seen[expression] {
+				seen[expression] = true
+				expressions = append(expressions, expression)
+			}
+		}
+	}
+	return expressions
+}
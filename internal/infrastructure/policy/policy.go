@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"net/url"
+	"shorter-rest-api/internal/infrastructure/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons reported for blocked destinations, threats of the blocklist are
+// reported as listed
+const (
+	ReasonNotAllowed = "domain is not on the allowlist"
+	ReasonDenied     = "domain is on the denylist"
+	ReasonNoHost     = "destination has no host"
+)
+
+// Options configures the destination policy
+type Options struct {
+	AllowDomains   []string      // Only these domains and their subdomains pass when set
+	DenyDomains    []string      // Domains and subdomains that never pass
+	BlocklistPath  string        // Hash-prefix blocklist file, empty disables it
+	ReloadInterval time.Duration // How often the blocklist file is checked for changes
+}
+
+// Engine vets destinations against domain allow and deny lists and a local
+// threat blocklist. The blocklist file is reloaded in the background when it
+// changes, so entries can be added without a restart.
+type Engine struct {
+	allow, deny []string
+	blocklist   *blocklistFile
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	reloading sync.WaitGroup
+}
+
+// New creates the policy engine, loading the blocklist file right away so
+// that a broken file fails at startup rather than going unnoticed
+func New(options Options) (*Engine, error) {
+	engine := &Engine{
+		allow: domainList(options.AllowDomains),
+		deny:  domainList(options.DenyDomains),
+		stop:  make(chan struct{}),
+	}
+	if options.BlocklistPath == "" {
+		return engine, nil
+	}
+	engine.blocklist = &blocklistFile{path: options.BlocklistPath}
+	if err := engine.blocklist.reload(); err != nil {
+		return nil, err
+	}
+	if options.ReloadInterval > 0 {
+		engine.reloading.Add(1)
+		go func() {
+			defer engine.reloading.Done()
+			engine.blocklist.watch(options.ReloadInterval, engine.stop)
+		}()
+	}
+	return engine, nil
+}
+
+// Check returns why destination must not be shortened or visited, empty
+// when it passes. The denylist and the blocklist win over the allowlist.
+func (e *Engine) Check(destination string) string {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return ""
+	}
+	// Destinations without a host, such as mailto:, only pass the scheme
+	// check. Web URLs without one, such as https:evil.example, cannot be
+	// vetted and never pass.
+	host := parsed.Hostname()
+	if host == "" {
+		if utils.NeedsHost(parsed.Scheme) {
+			return ReasonNoHost
+		}
+		return ""
+	}
+	if matchesDomain(host, e.deny) {
+		return ReasonDenied
+	}
+	if e.blocklist != nil {
+		if threat := e.blocklist.lookup(parsed); threat != "" {
+			return threat
+		}
+	}
+	if len(e.allow) > 0 && !matchesDomain(host, e.allow) {
+		return ReasonNotAllowed
+	}
+	return ""
+}
+
+// Reload reads the blocklist file again, keeping the entries in use when
+// it cannot be read
+func (e *Engine) Reload() error {
+	if e.blocklist == nil {
+		return nil
+	}
+	return e.blocklist.reload()
+}
+
+// Close stops watching the blocklist file
+func (e *Engine) Close() {
+	e.stopOnce.Do(func() { close(e.stop) })
+	e.reloading.Wait()
+}
+
+// domainList normalizes the domains of a list like the hosts of destinations
+func domainList(domains []string) []string {
+	list := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.TrimSpace(domain), "*.")
+		if normalized, err := utils.NormalizeHost(domain, ""); err == nil && normalized != "" {
+			list = append(list, normalized)
+		}
+	}
+	return list
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one
+func matchesDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Package ratelimit throttles clients with the generic cell rate algorithm
+// (GCRA), a token bucket that only stores the theoretical arrival time of
+// the next request per client.
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Limit allows Requests per Window, all of them in a burst at most
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// interval is the time one request takes out of the window
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// Result is the decision about one request
+type Result struct {
+	Allowed    bool
+	Remaining  int           // requests still allowed right now
+	ResetAfter time.Duration // until the full limit is available again
+	RetryAfter time.Duration // until the next request is allowed, 0 when allowed
+}
+
+// Limiter decides whether the client identified by key may make a request
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// gcra applies a request arriving at now to the theoretical arrival time
+// tat of the client and returns the new one with the decision
+func gcra(tat, now time.Time, limit Limit) (time.Time, Result) {
+	interval := limit.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	if allowAt := next.Add(-limit.Window); allowAt.After(now) {
+		return tat, Result{
+			ResetAfter: tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+	return next, Result{
+		Allowed:    true,
+		Remaining:  int((limit.Window - next.Sub(now)) / interval),
+		ResetAfter: next.Sub(now),
+	}
+}
+
+// fallbackLogInterval spaces out the logs about an unavailable limiter
+const fallbackLogInterval = 10 * time.Second
+
+type fallbackLimiter struct {
+	primary   Limiter
+	secondary Limiter
+	lastLog   atomic.Int64
+}
+
+// WithFallback returns a limiter asking primary and, when primary fails,
+// secondary. With a shared primary and a local secondary, nodes keep
+// limiting on their own while the shared store is down.
+func WithFallback(primary, secondary Limiter) Limiter {
+	return &fallbackLimiter{primary: primary, secondary: secondary}
+}
+
+func (f *fallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := f.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return result, nil
+	}
+	now := time.Now().UnixNano()
+	if last := f.lastLog.Load(); now-last >= int64(fallbackLogInterval) && f.lastLog.CompareAndSwap(last, now) {
+		log.Printf("rate limiter unavailable, limiting locally: %v", err)
+	}
+	return f.secondary.Allow(ctx, key, limit)
+}
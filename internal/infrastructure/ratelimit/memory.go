@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many requests pass between two sweeps of idle clients
+const sweepEvery = 1024
+
+// MemoryLimiter limits clients within a single process
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	arrivals map[string]time.Time
+	requests int
+	now      func() time.Time
+}
+
+// NewMemoryLimiter creates an in-memory limiter for single node deployments
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		arrivals: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Allow decides about a request of the client identified by key
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	if m.requests%sweepEvery == 0 {
+		// A client whose arrival time passed has its full limit back,
+		// forgetting it changes nothing
+		for client, tat := range m.arrivals {
+			if !tat.After(now) {
+				delete(m.arrivals, client)
+			}
+		}
+	}
+
+	tat, result := gcra(m.arrivals[key], now, limit)
+	if result.Allowed {
+		m.arrivals[key] = tat
+	}
+	return result, nil
+}
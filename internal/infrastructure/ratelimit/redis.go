@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "rate_limit:"
+
+// allowScript applies GCRA to the arrival time stored under KEYS[1]. The
+// Redis clock is used so that nodes with skewed clocks agree.
+//
+// KEYS[1] client arrival time in microseconds
+// ARGV[1] interval of one request in microseconds, ARGV[2] window in microseconds
+// Returns allowed (1 or 0), remaining, reset after and retry after in microseconds
+var allowScript = redis.NewScript(1, `
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+local interval = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tat < now then
+	tat = now
+end
+local nextTat = tat + interval
+local allowAt = nextTat - window
+if allowAt > now then
+	return {0, 0, tat - now, allowAt - now}
+end
+redis.call("SET", KEYS[1], string.format("%d", nextTat), "PX", math.ceil((nextTat - now) / 1000))
+return {1, math.floor((window - (nextTat - now)) / interval), nextTat - now, 0}
+`)
+
+// RedisLimiter limits clients across every node sharing a Redis server
+type RedisLimiter struct {
+	pool *redis.Pool
+}
+
+// NewRedisLimiter creates a limiter storing its state in Redis
+func NewRedisLimiter(pool *redis.Pool) *RedisLimiter {
+	return &RedisLimiter{pool: pool}
+}
+
+// Allow decides about a request of the client identified by key
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get redis connection: %w", err)
+	}
+	defer conn.Close()
+
+	values, err := redis.Int64s(allowScript.Do(conn, keyPrefix+key, limit.interval().Microseconds(), limit.Window.Microseconds()))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to apply rate limit: %w", err)
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		ResetAfter: time.Duration(values[2]) * time.Microsecond,
+		RetryAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,438 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	linksBucket         = []byte("short_urls")
+	originsBucket       = []byte("short_url_origins")
+	createdIndexBucket  = []byte("short_urls_by_created")
+	idempotencyBucket   = []byte("idempotency_keys")
+	clicksBucket        = []byte("click_stats")
+	apiKeysBucket       = []byte("api_keys")
+	apiKeyHashesBucket  = []byte("api_key_hashes")
+	dailyCreatesBucket  = []byte("quota_daily_creates")
+	usageBucket         = []byte("short_url_usage")
+	usageCountsBucket   = []byte("short_url_usage_counts")
+	filterIndexesBucket = []byte("short_url_indexes")
+)
+
+type boltLink struct {
+	Link      entity.ShortURL `json:"link"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Consumed  int             `json:"consumed,omitempty"` // redirects counted against the click limit
+}
+
+// boltCode points at a code, used by the reverse and idempotency indexes
+type boltCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// BoltStore is a link repository persisted in a single embedded bbolt file
+type BoltStore struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// NewBoltStore opens (or creates) the bbolt database file at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage file: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		counted := tx.Bucket(usageBucket) != nil
+		indexed := tx.Bucket(filterIndexesBucket) != nil
+		for _, name := range [][]byte{linksBucket, originsBucket, createdIndexBucket, idempotencyBucket, clicksBucket, apiKeysBucket, apiKeyHashesBucket, dailyCreatesBucket,
+			usageBucket, usageCountsBucket, filterIndexesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if !counted {
+			if err := rebuildUsage(tx, time.Now()); err != nil {
+				return err
+			}
+		}
+		if !indexed {
+			return rebuildFilterIndexes(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create storage buckets: %w", err)
+	}
+	return &BoltStore{db: db, now: time.Now}, nil
+}
+
+// Create atomically reserves the code and stores the short URL with its
+// reverse index in one transaction, which also checks the link limits
+func (s *BoltStore) Create(ctx context.Context, link *entity.ShortURL, ttl time.Duration, limits repository.LinkLimits) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.now().Add(ttl)
+	}
+	rawLink, err := json.Marshal(boltLink{Link: *link, ExpiresAt: expiresAt})
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	key := link.Key()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		existing, err := s.getLink(tx, key)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return repository.ErrCodeAlreadyExists
+		}
+		if err := s.removeStale(tx, key); err != nil {
+			return err
+		}
+		if err := checkUsageLimits(tx, link, limits, s.now()); err != nil {
+			return err
+		}
+		if err := tx.Bucket(linksBucket).Put([]byte(key), rawLink); err != nil {
+			return fmt.Errorf("failed to save short url: %w", err)
+		}
+		if err := addUsage(tx, link, s.now()); err != nil {
+			return err
+		}
+		if err := addToIndexes(tx, link); err != nil {
+			return err
+		}
+		// A new link under the code of an expired one starts without its stats
+		if err := dropClicks(tx, key); err != nil {
+			return err
+		}
+		if err := s.claimOrigin(tx, entity.OriginKey(link.Domain, link.OwnerID, link.OriginalURL), boltCode{Code: key, ExpiresAt: expiresAt}); err != nil {
+			return fmt.Errorf("failed to save original url: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update replaces an existing short URL, its expiration and reverse entry
+func (s *BoltStore) Update(ctx context.Context, link *entity.ShortURL, ttl time.Duration) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.now().Add(ttl)
+	}
+	key := link.Key()
+	originKey := entity.OriginKey(link.Domain, link.OwnerID, link.OriginalURL)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		previous, err := s.getLink(tx, key)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			return repository.ErrLinkNotFound
+		}
+		rawLink, err := json.Marshal(boltLink{Link: *link, ExpiresAt: expiresAt, Consumed: previous.Consumed})
+		if err != nil {
+			return fmt.Errorf("failed to marshal value: %w", err)
+		}
+		if previousOrigin := entity.OriginKey(previous.Link.Domain, previous.Link.OwnerID, previous.Link.OriginalURL); previousOrigin != originKey {
+			if err := s.dropOrigin(tx, previousOrigin, key); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(linksBucket).Put([]byte(key), rawLink); err != nil {
+			return fmt.Errorf("failed to update short url: %w", err)
+		}
+		if err := removeUsage(tx, &previous.Link); err != nil {
+			return err
+		}
+		if err := addUsage(tx, link, s.now()); err != nil {
+			return err
+		}
+		if err := removeFromIndexes(tx, &previous.Link); err != nil {
+			return err
+		}
+		if err := addToIndexes(tx, link); err != nil {
+			return err
+		}
+
+		if link.IsDeleted() {
+			return s.dropOrigin(tx, originKey, key)
+		}
+		return s.claimOrigin(tx, originKey, boltCode{Code: key, ExpiresAt: expiresAt})
+	})
+}
+
+// Delete removes a short URL, its reverse entry and its click stats
+func (s *BoltStore) Delete(ctx context.Context, code string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		record, err := s.getLink(tx, code)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return repository.ErrLinkNotFound
+		}
+		if err := s.dropOrigin(tx, entity.OriginKey(record.Link.Domain, record.Link.OwnerID, record.Link.OriginalURL), code); err != nil {
+			return err
+		}
+		if err := removeFromIndexes(tx, &record.Link); err != nil {
+			return err
+		}
+		if err := dropClicks(tx, code); err != nil {
+			return err
+		}
+		if err := removeUsage(tx, &record.Link); err != nil {
+			return err
+		}
+		return tx.Bucket(linksBucket).Delete([]byte(code))
+	})
+}
+
+// GetByCode gets a short URL by code
+func (s *BoltStore) GetByCode(ctx context.Context, code string) (*entity.ShortURL, error) {
+	var record *boltLink
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		record, err = s.getLink(tx, code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, repository.ErrLinkNotFound
+	}
+	return &record.Link, nil
+}
+
+// GetByOriginalURL follows the reverse index to the short URL of owner for the original URL on domain
+func (s *BoltStore) GetByOriginalURL(ctx context.Context, domain, ownerID, originalURL string) (*entity.ShortURL, error) {
+	var record *boltLink
+	err := s.db.View(func(tx *bolt.Tx) error {
+		origin, err := s.getCode(tx, originsBucket, entity.OriginKey(domain, ownerID, originalURL))
+		if err != nil || origin == nil {
+			return err
+		}
+		record, err = s.getLink(tx, origin.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, repository.ErrLinkNotFound
+	}
+	return &record.Link, nil
+}
+
+// ClaimIdempotencyKey claims an unused key, returning the recorded code when already claimed
+func (s *BoltStore) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	var code string
+	var claimed bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		entry, err := s.getCode(tx, idempotencyBucket, key)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			code = entry.Code
+			return nil
+		}
+		claimed = true
+		return s.putCode(tx, idempotencyBucket, key, boltCode{ExpiresAt: s.now().Add(ttl)})
+	})
+	return code, claimed, err
+}
+
+// CompleteIdempotencyKey records the code created for the key
+func (s *BoltStore) CompleteIdempotencyKey(ctx context.Context, key, code string, ttl time.Duration) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return s.putCode(tx, idempotencyBucket, key, boltCode{Code: code, ExpiresAt: s.now().Add(ttl)})
+	})
+}
+
+// ReleaseIdempotencyKey removes the claim on the key
+func (s *BoltStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(idempotencyBucket).Delete([]byte(key))
+	})
+}
+
+// List walks the most selective index for the filter from the cursor and
+// returns a page of the live links matching the filter
+func (s *BoltStore) List(ctx context.Context, filter repository.LinkFilter) ([]*entity.ShortURL, error) {
+	var links []*entity.ShortURL
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := listIndex(tx, filter)
+		if bucket == nil {
+			return nil
+		}
+		index := bucket.Cursor()
+		key, next := s.listStart(index, filter)
+		for ; key != nil && len(links) < filter.Limit; key, _ = next() {
+			position := parseCreatedIndexKey(key)
+			if filter.Descending && filter.CreatedFrom != nil && position.CreatedAt < filter.CreatedFrom.UnixMilli() {
+				break
+			}
+			if !filter.Descending && filter.CreatedTo != nil && position.CreatedAt > filter.CreatedTo.UnixMilli() {
+				break
+			}
+			if !filter.IsAfterCursor(position) {
+				continue
+			}
+			record, err := s.getLink(tx, position.Code)
+			if err != nil {
+				return err
+			}
+			if record != nil && filter.Matches(&record.Link) {
+				link := record.Link
+				links = append(links, &link)
+			}
+		}
+		return nil
+	})
+	return links, err
+}
+
+// listStart positions the index cursor at the first candidate of the
+// listing and returns it with the step function for the requested order
+func (s *BoltStore) listStart(index *bolt.Cursor, filter repository.LinkFilter) ([]byte, func() ([]byte, []byte)) {
+	if !filter.Descending {
+		var from int64
+		if filter.CreatedFrom != nil {
+			from = filter.CreatedFrom.UnixMilli()
+		}
+		if filter.After != nil && filter.After.CreatedAt > from {
+			from = filter.After.CreatedAt
+		}
+		key, _ := index.Seek(createdIndexKey(repository.LinkCursor{CreatedAt: from}))
+		return key, index.Next
+	}
+
+	var to int64 = -1
+	if filter.CreatedTo != nil {
+		to = filter.CreatedTo.UnixMilli()
+	}
+	if filter.After != nil && (to < 0 || filter.After.CreatedAt < to) {
+		to = filter.After.CreatedAt
+	}
+	if to < 0 {
+		key, _ := index.Last()
+		return key, index.Prev
+	}
+	// Seek to the first key past the upper bound and step back from there
+	key, _ := index.Seek(createdIndexKey(repository.LinkCursor{CreatedAt: to + 1}))
+	if key == nil {
+		key, _ = index.Last()
+	} else {
+		key, _ = index.Prev()
+	}
+	return key, index.Prev
+}
+
+// Close closes the database file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// getLink reads a live link record, returning nil when missing or expired
+func (s *BoltStore) getLink(tx *bolt.Tx, code string) (*boltLink, error) {
+	rawData := tx.Bucket(linksBucket).Get([]byte(code))
+	if rawData == nil {
+		return nil, nil
+	}
+	var record boltLink
+	if err := json.Unmarshal(rawData, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	if expired(record.ExpiresAt, s.now()) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// removeStale stops counting and listing the expired record a create
+// replaces
+func (s *BoltStore) removeStale(tx *bolt.Tx, code string) error {
+	rawData := tx.Bucket(linksBucket).Get([]byte(code))
+	if rawData == nil {
+		return nil
+	}
+	var stale boltLink
+	if err := json.Unmarshal(rawData, &stale); err != nil {
+		return fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	if err := removeUsage(tx, &stale.Link); err != nil {
+		return err
+	}
+	return removeFromIndexes(tx, &stale.Link)
+}
+
+// getCode reads a live code pointer from bucket, returning nil when missing or expired
+func (s *BoltStore) getCode(tx *bolt.Tx, bucket []byte, key string) (*boltCode, error) {
+	rawData := tx.Bucket(bucket).Get([]byte(key))
+	if rawData == nil {
+		return nil, nil
+	}
+	var entry boltCode
+	if err := json.Unmarshal(rawData, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	if expired(entry.ExpiresAt, s.now()) {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *BoltStore) putCode(tx *bolt.Tx, bucket []byte, key string, entry boltCode) error {
+	rawData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return tx.Bucket(bucket).Put([]byte(key), rawData)
+}
+
+// claimOrigin points the reverse entry of originKey at the code of entry
+// unless it belongs to another live link
+func (s *BoltStore) claimOrigin(tx *bolt.Tx, originKey string, entry boltCode) error {
+	origin, err := s.getCode(tx, originsBucket, originKey)
+	if err != nil {
+		return err
+	}
+	if origin != nil && origin.Code != entry.Code {
+		return nil
+	}
+	return s.putCode(tx, originsBucket, originKey, entry)
+}
+
+// dropOrigin removes the reverse entry of originKey when it points at code
+func (s *BoltStore) dropOrigin(tx *bolt.Tx, originKey, code string) error {
+	origin, err := s.getCode(tx, originsBucket, originKey)
+	if err != nil || origin == nil || origin.Code != code {
+		return err
+	}
+	return tx.Bucket(originsBucket).Delete([]byte(originKey))
+}
+
+// createdIndexKey orders index entries by creation time and then by code
+func createdIndexKey(position repository.LinkCursor) []byte {
+	key := make([]byte, 8, 8+len(position.Code))
+	binary.BigEndian.PutUint64(key, uint64(position.CreatedAt))
+	return append(key, position.Code...)
+}
+
+func parseCreatedIndexKey(key []byte) repository.LinkCursor {
+	return repository.LinkCursor{
+		CreatedAt: int64(binary.BigEndian.Uint64(key[:8])),
+		Code:      string(key[8:]),
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CreateAPIKey stores a new API key
+func (s *BoltStore) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(apiKeysBucket).Get([]byte(key.ID)) != nil {
+			return fmt.Errorf("api key %s already exists", key.ID)
+		}
+		return s.putAPIKey(tx, key)
+	})
+}
+
+// UpdateAPIKey overwrites an existing API key
+func (s *BoltStore) UpdateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		previous, err := s.getAPIKey(tx, key.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(apiKeyHashesBucket).Delete([]byte(previous.Hash)); err != nil {
+			return err
+		}
+		return s.putAPIKey(tx, key)
+	})
+}
+
+// GetAPIKey gets an API key by ID
+func (s *BoltStore) GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error) {
+	var key *entity.APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		key, err = s.getAPIKey(tx, id)
+		return err
+	})
+	return key, err
+}
+
+// GetAPIKeyByHash follows the hash lookup to an API key
+func (s *BoltStore) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	var key *entity.APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(apiKeyHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return repository.ErrAPIKeyNotFound
+		}
+		var err error
+		key, err = s.getAPIKey(tx, string(id))
+		return err
+	})
+	return key, err
+}
+
+// ListAPIKeys returns every API key in creation order
+func (s *BoltStore) ListAPIKeys(ctx context.Context) ([]*entity.APIKey, error) {
+	var keys []*entity.APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(apiKeysBucket).ForEach(func(_, rawData []byte) error {
+			var key entity.APIKey
+			if err := json.Unmarshal(rawData, &key); err != nil {
+				return fmt.Errorf("failed to unmarshal value: %w", err)
+			}
+			keys = append(keys, &key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *BoltStore) getAPIKey(tx *bolt.Tx, id string) (*entity.APIKey, error) {
+	rawData := tx.Bucket(apiKeysBucket).Get([]byte(id))
+	if rawData == nil {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	var key entity.APIKey
+	if err := json.Unmarshal(rawData, &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return &key, nil
+}
+
+// putAPIKey writes a key and its hash lookup
+func (s *BoltStore) putAPIKey(tx *bolt.Tx, key *entity.APIKey) error {
+	rawData, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if err := tx.Bucket(apiKeysBucket).Put([]byte(key.ID), rawData); err != nil {
+		return err
+	}
+	return tx.Bucket(apiKeyHashesBucket).Put([]byte(key.Hash), []byte(key.ID))
+}
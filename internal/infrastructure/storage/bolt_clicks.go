@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"shorter-rest-api/internal/domain/repository"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The redirects counted against a click limit are kept in the link record,
+// so they share its transaction, expiry and deletion
+
+// ConsumeClick counts one redirect of the link under code unless limit is reached
+func (s *BoltStore) ConsumeClick(ctx context.Context, code string, limit int) (int, bool, error) {
+	consumed, counted := 0, false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		record, err := s.getLink(tx, code)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return repository.ErrLinkNotFound
+		}
+		consumed = record.Consumed
+		if consumed >= limit {
+			return nil
+		}
+		record.Consumed++
+		rawLink, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value: %w", err)
+		}
+		if err := tx.Bucket(linksBucket).Put([]byte(code), rawLink); err != nil {
+			return fmt.Errorf("failed to count click: %w", err)
+		}
+		consumed, counted = record.Consumed, true
+		return nil
+	})
+	return consumed, counted, err
+}
+
+// ConsumedClicks returns the redirects counted against the click limit of code
+func (s *BoltStore) ConsumedClicks(ctx context.Context, code string) (int, error) {
+	consumed := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		record, err := s.getLink(tx, code)
+		if record != nil {
+			consumed = record.Consumed
+		}
+		return err
+	})
+	return consumed, err
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Links are listed in the creation time index and, like in the Redis store,
+// in an index per destination host, tag and owner: nested buckets of
+// short_url_indexes keyed like the creation time index, so that filtered
+// listings only walk the links of the most selective one. Every version of
+// a link leaves its indexes in the transaction replacing it.
+const (
+	hostIndexPrefix  = "host:"
+	tagIndexPrefix   = "tag:"
+	ownerIndexPrefix = "owner:"
+)
+
+// filterIndexes returns the indexes listing the link besides the creation
+// time index
+func filterIndexes(link *entity.ShortURL) []string {
+	var names []string
+	if host := link.Host(); host != "" {
+		names = append(names, hostIndexPrefix+host)
+	}
+	for _, tag := range link.Tags {
+		names = append(names, tagIndexPrefix+tag)
+	}
+	if link.OwnerID != "" {
+		names = append(names, ownerIndexPrefix+link.OwnerID)
+	}
+	return names
+}
+
+// addToIndexes lists the link in the creation time index and its filter
+// indexes
+func addToIndexes(tx *bolt.Tx, link *entity.ShortURL) error {
+	entry := createdIndexKey(repository.CursorOf(link))
+	if err := tx.Bucket(createdIndexBucket).Put(entry, nil); err != nil {
+		return fmt.Errorf("failed to index short url: %w", err)
+	}
+	return addToFilterIndexes(tx, link)
+}
+
+func addToFilterIndexes(tx *bolt.Tx, link *entity.ShortURL) error {
+	entry := createdIndexKey(repository.CursorOf(link))
+	for _, name := range filterIndexes(link) {
+		index, err := tx.Bucket(filterIndexesBucket).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return fmt.Errorf("failed to index short url: %w", err)
+		}
+		if err := index.Put(entry, nil); err != nil {
+			return fmt.Errorf("failed to index short url: %w", err)
+		}
+	}
+	return nil
+}
+
+// removeFromIndexes stops listing a stored version of a link, dropping the
+// filter indexes it leaves empty
+func removeFromIndexes(tx *bolt.Tx, link *entity.ShortURL) error {
+	entry := createdIndexKey(repository.CursorOf(link))
+	if err := tx.Bucket(createdIndexBucket).Delete(entry); err != nil {
+		return fmt.Errorf("failed to unindex short url: %w", err)
+	}
+	for _, name := range filterIndexes(link) {
+		index := tx.Bucket(filterIndexesBucket).Bucket([]byte(name))
+		if index == nil {
+			continue
+		}
+		if err := index.Delete(entry); err != nil {
+			return fmt.Errorf("failed to unindex short url: %w", err)
+		}
+		if first, _ := index.Cursor().First(); first == nil {
+			if err := tx.Bucket(filterIndexesBucket).DeleteBucket([]byte(name)); err != nil {
+				return fmt.Errorf("failed to unindex short url: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// listIndex returns the most selective index for the filter, nil when no
+// link matches it
+func listIndex(tx *bolt.Tx, filter repository.LinkFilter) *bolt.Bucket {
+	var name string
+	switch {
+	case filter.Tag != "":
+		name = tagIndexPrefix + filter.Tag
+	case filter.Host != "":
+		name = hostIndexPrefix + filter.Host
+	case filter.OwnerID != "":
+		name = ownerIndexPrefix + filter.OwnerID
+	default:
+		return tx.Bucket(createdIndexBucket)
+	}
+	return tx.Bucket(filterIndexesBucket).Bucket([]byte(name))
+}
+
+// rebuildFilterIndexes indexes the links of a file written before the
+// filter indexes existed
+func rebuildFilterIndexes(tx *bolt.Tx) error {
+	return tx.Bucket(linksBucket).ForEach(func(_, rawData []byte) error {
+		var record boltLink
+		if err := json.Unmarshal(rawData, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal value: %w", err)
+		}
+		return addToFilterIndexes(tx, &record.Link)
+	})
+}
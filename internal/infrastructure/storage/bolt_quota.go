@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Daily create counters are stored per owner as the day followed by the
+// 8-byte big-endian count, a counter of an earlier day counts as zero
+
+// ReserveDailyCreate counts one create of owner for the day unless limit is reached
+func (s *BoltStore) ReserveDailyCreate(ctx context.Context, ownerID string, day time.Time, limit int) (int, bool, error) {
+	key := repository.QuotaDay(day)
+	creates, reserved := 0, false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dailyCreatesBucket)
+		creates = boltDailyCreates(bucket.Get([]byte(ownerID)), key)
+		if creates >= limit {
+			return nil
+		}
+		creates++
+		reserved = true
+		return bucket.Put([]byte(ownerID), encodeDailyCreates(key, creates))
+	})
+	return creates, reserved, err
+}
+
+// ReleaseDailyCreate gives back a create reserved for the day
+func (s *BoltStore) ReleaseDailyCreate(ctx context.Context, ownerID string, day time.Time) error {
+	key := repository.QuotaDay(day)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dailyCreatesBucket)
+		creates := boltDailyCreates(bucket.Get([]byte(ownerID)), key)
+		if creates == 0 {
+			return nil
+		}
+		return bucket.Put([]byte(ownerID), encodeDailyCreates(key, creates-1))
+	})
+}
+
+// boltDailyCreates decodes the count of a stored counter when it belongs to day
+func boltDailyCreates(raw []byte, day string) int {
+	if len(raw) != len(day)+8 || string(raw[:len(day)]) != day {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(raw[len(day):]))
+}
+
+func encodeDailyCreates(day string, creates int) []byte {
+	raw := make([]byte, len(day)+8)
+	copy(raw, day)
+	binary.BigEndian.PutUint64(raw[len(day):], uint64(creates))
+	return raw
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const visitorsKeyName = "visitors"
+
+// clicksKey lays a counter out as "<name>:" followed by the big-endian bucket
+// start and the breakdown value, so the buckets of one name sort by time.
+// Each link keeps its counters in its own nested bucket of click_stats.
+// Unique visitor sketches are stored under "visitors:" and the day start.
+func clicksKey(name string, start time.Time, value string) []byte {
+	key := make([]byte, 0, len(name)+9+len(value))
+	key = append(key, name...)
+	key = append(key, ':')
+	key = binary.BigEndian.AppendUint64(key, uint64(start.Unix()))
+	return append(key, value...)
+}
+
+// dropClicks removes the click counters of a link
+func dropClicks(tx *bolt.Tx, code string) error {
+	err := tx.Bucket(clicksBucket).DeleteBucket([]byte(code))
+	if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+		return fmt.Errorf("failed to delete click stats: %w", err)
+	}
+	return nil
+}
+
+// RecordClicks increments the counters of a batch of clicks in one transaction
+func (s *BoltStore) RecordClicks(ctx context.Context, events []*entity.ClickEvent) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, event := range events {
+			if err := s.recordClick(tx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record clicks: %w", err)
+	}
+	return nil
+}
+
+// recordClick increments the counters of one click
+func (s *BoltStore) recordClick(tx *bolt.Tx, event *entity.ClickEvent) error {
+	clicks, err := tx.Bucket(clicksBucket).CreateBucketIfNotExists([]byte(event.Code))
+	if err != nil {
+		return err
+	}
+	var keys [][]byte
+	for _, granularity := range []entity.Granularity{entity.GranularityMinute, entity.GranularityHour, entity.GranularityDay} {
+		keys = append(keys, clicksKey(string(granularity), granularity.BucketStart(event.OccurredAt), ""))
+	}
+	day := entity.GranularityDay.BucketStart(event.OccurredAt)
+	for dimension, value := range event.Dimensions() {
+		keys = append(keys, clicksKey(dimension, day, value))
+	}
+	for _, key := range keys {
+		var count uint64
+		if rawData := clicks.Get(key); len(rawData) == 8 {
+			count = binary.BigEndian.Uint64(rawData)
+		}
+		if err := clicks.Put(key, binary.BigEndian.AppendUint64(nil, count+1)); err != nil {
+			return err
+		}
+	}
+	if event.VisitorID == "" {
+		return nil
+	}
+	key := clicksKey(visitorsKeyName, day, "")
+	visitors := newHyperLogLog()
+	if rawData := clicks.Get(key); len(rawData) == hllRegisters {
+		copy(visitors, rawData)
+	}
+	if !visitors.add(event.VisitorID) {
+		return nil
+	}
+	return clicks.Put(key, visitors)
+}
+
+// GetClickStats returns the counters of the query range
+func (s *BoltStore) GetClickStats(ctx context.Context, query repository.StatsQuery) (*entity.ClickStats, error) {
+	stats := &entity.ClickStats{Breakdowns: make(map[string]map[string]int64)}
+	dimensions := []string{entity.DimensionReferrer, entity.DimensionBrowser, entity.DimensionCountry}
+	for _, dimension := range dimensions {
+		stats.Breakdowns[dimension] = make(map[string]int64)
+	}
+
+	counts := make(map[int64]int64)
+	visitors := make(map[int64]int64)
+	union := newHyperLogLog()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		clicks := tx.Bucket(clicksBucket).Bucket([]byte(query.Code))
+		if clicks == nil {
+			return nil
+		}
+		name := string(query.Granularity)
+		cursor := clicks.Cursor()
+		last := clicksKey(name, query.Granularity.BucketStart(query.To), "")
+		for key, rawData := cursor.Seek(clicksKey(name, query.Granularity.BucketStart(query.From), "")); key != nil && bytes.Compare(key, last) <= 0; key, rawData = cursor.Next() {
+			start := int64(binary.BigEndian.Uint64(key[len(name)+1:]))
+			counts[start] = int64(binary.BigEndian.Uint64(rawData))
+		}
+		for _, dimension := range dimensions {
+			for _, day := range query.Days() {
+				prefix := clicksKey(dimension, day, "")
+				for key, rawData := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, rawData = cursor.Next() {
+					stats.Breakdowns[dimension][string(key[len(prefix):])] += int64(binary.BigEndian.Uint64(rawData))
+				}
+			}
+		}
+		for _, day := range query.Days() {
+			if rawData := clicks.Get(clicksKey(visitorsKeyName, day, "")); len(rawData) == hllRegisters {
+				union.merge(hyperLogLog(rawData))
+				visitors[day.Unix()] = hyperLogLog(rawData).count()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click stats: %w", err)
+	}
+	stats.Series = query.Series(counts)
+	for _, day := range query.Days() {
+		stats.DailyVisitors = append(stats.DailyVisitors, entity.VisitorBucket{Start: day, Visitors: visitors[day.Unix()]})
+	}
+	stats.UniqueVisitors = union.count()
+	return stats, nil
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Active links are counted per scope, one for all links and one per owner.
+// Each scope has a nested bucket of short_url_usage holding an entry per
+// active link, keyed by the big-endian expiry of the link in Unix
+// milliseconds followed by its code, and a counter in
+// short_url_usage_counts. Links leave the entries when they are updated,
+// soft deleted or deleted in the same transaction; expired entries sort
+// first and are trimmed when counting, so a count reads a counter instead
+// of scanning every link.
+const (
+	usageAll         = "all"
+	usageOwnerPrefix = "owner:"
+)
+
+// usageScopes returns the scopes counting the link
+func usageScopes(link *entity.ShortURL) []string {
+	scopes := []string{usageAll}
+	if link.OwnerID != "" {
+		scopes = append(scopes, usageOwnerPrefix+link.OwnerID)
+	}
+	return scopes
+}
+
+// usageEntry returns the key of the link in the entries of its scopes,
+// links that never expire sorting last
+func usageEntry(link *entity.ShortURL) []byte {
+	expiry := uint64(math.MaxUint64)
+	if link.ExpiresAt != nil {
+		expiry = uint64(link.ExpiresAt.UnixMilli())
+	}
+	code := link.Key()
+	key := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(code)), expiry)
+	return append(key, code...)
+}
+
+// addUsage counts the link in its scopes when it is active at now
+func addUsage(tx *bolt.Tx, link *entity.ShortURL, now time.Time) error {
+	if !link.IsActive(now) {
+		return nil
+	}
+	entry := usageEntry(link)
+	for _, scope := range usageScopes(link) {
+		entries, err := tx.Bucket(usageBucket).CreateBucketIfNotExists([]byte(scope))
+		if err != nil {
+			return fmt.Errorf("failed to count short url: %w", err)
+		}
+		if entries.Get(entry) != nil {
+			continue
+		}
+		if err := entries.Put(entry, []byte{1}); err != nil {
+			return fmt.Errorf("failed to count short url: %w", err)
+		}
+		if err := addUsageCount(tx, scope, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUsage stops counting a stored version of a link
+func removeUsage(tx *bolt.Tx, link *entity.ShortURL) error {
+	entry := usageEntry(link)
+	for _, scope := range usageScopes(link) {
+		entries := tx.Bucket(usageBucket).Bucket([]byte(scope))
+		if entries == nil || entries.Get(entry) == nil {
+			continue
+		}
+		if err := entries.Delete(entry); err != nil {
+			return fmt.Errorf("failed to uncount short url: %w", err)
+		}
+		if err := addUsageCount(tx, scope, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addUsageCount(tx *bolt.Tx, scope string, delta int) error {
+	count := usageCount(tx, scope) + delta
+	if count < 0 {
+		count = 0
+	}
+	return tx.Bucket(usageCountsBucket).Put([]byte(scope), binary.BigEndian.AppendUint64(nil, uint64(count)))
+}
+
+func usageCount(tx *bolt.Tx, scope string) int {
+	rawData := tx.Bucket(usageCountsBucket).Get([]byte(scope))
+	if len(rawData) != 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(rawData))
+}
+
+// trimUsage drops the entries of scope that expired at now and returns the
+// links still counted
+func trimUsage(tx *bolt.Tx, scope string, now time.Time) (int, error) {
+	entries := tx.Bucket(usageBucket).Bucket([]byte(scope))
+	if entries == nil {
+		return 0, nil
+	}
+	cursor := entries.Cursor()
+	trimmed := 0
+	for key, _ := cursor.First(); key != nil && binary.BigEndian.Uint64(key[:8]) <= uint64(now.UnixMilli()); key, _ = cursor.First() {
+		if err := cursor.Delete(); err != nil {
+			return 0, fmt.Errorf("failed to trim expired short urls: %w", err)
+		}
+		trimmed++
+	}
+	if trimmed > 0 {
+		if err := addUsageCount(tx, scope, -trimmed); err != nil {
+			return 0, err
+		}
+	}
+	return usageCount(tx, scope), nil
+}
+
+// checkUsageLimits fails when counting the link, if active, would take its
+// scopes over limits
+func checkUsageLimits(tx *bolt.Tx, link *entity.ShortURL, limits repository.LinkLimits, now time.Time) error {
+	if !link.IsActive(now) {
+		return nil
+	}
+	if limits.Total > 0 {
+		count, err := trimUsage(tx, usageAll, now)
+		if err != nil {
+			return err
+		}
+		if count >= limits.Total {
+			return repository.ErrTotalLinkLimit
+		}
+	}
+	if limits.Owner > 0 && link.OwnerID != "" {
+		count, err := trimUsage(tx, usageOwnerPrefix+link.OwnerID, now)
+		if err != nil {
+			return err
+		}
+		if count >= limits.Owner {
+			return repository.ErrOwnerLinkLimit
+		}
+	}
+	return nil
+}
+
+// rebuildUsage counts the live links of a file written before the usage
+// counters existed
+func rebuildUsage(tx *bolt.Tx, now time.Time) error {
+	return tx.Bucket(linksBucket).ForEach(func(_, rawData []byte) error {
+		var record boltLink
+		if err := json.Unmarshal(rawData, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal value: %w", err)
+		}
+		if expired(record.ExpiresAt, now) {
+			return nil
+		}
+		return addUsage(tx, &record.Link, now)
+	})
+}
+
+// Count counts the active short URLs
+func (s *BoltStore) Count(ctx context.Context) (int, error) {
+	return s.countUsage(usageAll)
+}
+
+// CountByOwner counts the active short URLs of an API key
+func (s *BoltStore) CountByOwner(ctx context.Context, ownerID string) (int, error) {
+	return s.countUsage(usageOwnerPrefix + ownerID)
+}
+
+// countUsage trims the expired entries of a scope and reads its counter
+func (s *BoltStore) countUsage(scope string) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		count, err = trimUsage(tx, scope, s.now())
+		return err
+	})
+	return count, err
+}
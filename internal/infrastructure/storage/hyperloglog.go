@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision gives 4096 one-byte registers per sketch, a standard error
+// of about 1.6%
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog estimates the number of distinct values added to it. It is
+// the in-process counterpart of Redis PFADD/PFCOUNT for the memory and file
+// backends.
+type hyperLogLog []uint8
+
+func newHyperLogLog() hyperLogLog {
+	return make(hyperLogLog, hllRegisters)
+}
+
+// hllHash spreads a value over 64 bits: FNV-1a followed by the splitmix64
+// finalizer, as FNV alone mixes its high bits poorly
+func hllHash(value string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	hash := hasher.Sum64()
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	return hash ^ hash>>31
+}
+
+// add records value, reporting whether the sketch changed
+func (h hyperLogLog) add(value string) bool {
+	hash := hllHash(value)
+	index := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank <= h[index] {
+		return false
+	}
+	h[index] = rank
+	return true
+}
+
+// merge folds other into h
+func (h hyperLogLog) merge(other hyperLogLog) {
+	for i, rank := range other {
+		if rank > h[i] {
+			h[i] = rank
+		}
+	}
+}
+
+// count estimates the number of distinct values, using linear counting
+// while many registers are still empty
+func (h hyperLogLog) count() int64 {
+	sum, zeros := 0.0, 0
+	for _, rank := range h {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
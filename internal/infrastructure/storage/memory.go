@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryRecord struct {
+	link      entity.ShortURL
+	expiresAt time.Time // zero means the record never expires
+	consumed  int       // redirects counted against the click limit
+}
+
+// memoryCode points at a code, used by the reverse and idempotency indexes
+type memoryCode struct {
+	code      string
+	expiresAt time.Time
+}
+
+// expired reports whether a record with the given expiry is gone at now
+func expired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
+
+// MemoryStore is a thread-safe in-memory link repository for tests and development
+type MemoryStore struct {
+	mu           sync.RWMutex
+	links        map[string]memoryRecord
+	origins      map[string]memoryCode
+	idempotency  map[string]memoryCode
+	clicks       map[string]*memoryClicks
+	apiKeys      map[string]entity.APIKey
+	apiKeyHashes map[string]string
+	dailyCreates map[string]memoryDailyCreates
+	usage        map[string]*memoryUsage
+	now          func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory link repository
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		links:        make(map[string]memoryRecord),
+		origins:      make(map[string]memoryCode),
+		idempotency:  make(map[string]memoryCode),
+		clicks:       make(map[string]*memoryClicks),
+		apiKeys:      make(map[string]entity.APIKey),
+		apiKeyHashes: make(map[string]string),
+		dailyCreates: make(map[string]memoryDailyCreates),
+		usage:        make(map[string]*memoryUsage),
+		now:          time.Now,
+	}
+}
+
+// Create atomically reserves the code and stores the short URL with its reverse index
+func (s *MemoryStore) Create(ctx context.Context, link *entity.ShortURL, ttl time.Duration, limits repository.LinkLimits) error {
+	now := s.now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	key := link.Key()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.links[key]; ok {
+		if !expired(record.expiresAt, now) {
+			return repository.ErrCodeAlreadyExists
+		}
+		s.removeUsageLocked(&record.link)
+	}
+	if err := s.checkUsageLimitsLocked(link, limits, now); err != nil {
+		return err
+	}
+	s.links[key] = memoryRecord{link: *link, expiresAt: expiresAt}
+	s.addUsageLocked(link, expiresAt, now)
+	// A new link under the code of an expired one starts without its stats
+	delete(s.clicks, key)
+	s.claimOrigin(entity.OriginKey(link.Domain, link.OwnerID, link.OriginalURL), key, expiresAt, now)
+	return nil
+}
+
+// Update replaces an existing short URL, its expiration and reverse entry
+func (s *MemoryStore) Update(ctx context.Context, link *entity.ShortURL, ttl time.Duration) error {
+	now := s.now()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+
+	key := link.Key()
+	originKey := entity.OriginKey(link.Domain, link.OwnerID, link.OriginalURL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.links[key]
+	if !ok || expired(previous.expiresAt, now) {
+		return repository.ErrLinkNotFound
+	}
+	if previousOrigin := entity.OriginKey(previous.link.Domain, previous.link.OwnerID, previous.link.OriginalURL); previousOrigin != originKey {
+		s.dropOrigin(previousOrigin, key)
+	}
+	s.links[key] = memoryRecord{link: *link, expiresAt: expiresAt, consumed: previous.consumed}
+	s.removeUsageLocked(&previous.link)
+	s.addUsageLocked(link, expiresAt, now)
+
+	if link.IsDeleted() {
+		s.dropOrigin(originKey, key)
+		return nil
+	}
+	s.claimOrigin(originKey, key, expiresAt, now)
+	return nil
+}
+
+// Delete removes a short URL, its reverse entry and its click stats
+func (s *MemoryStore) Delete(ctx context.Context, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.links[code]
+	if !ok || expired(record.expiresAt, s.now()) {
+		return repository.ErrLinkNotFound
+	}
+	s.dropOrigin(entity.OriginKey(record.link.Domain, record.link.OwnerID, record.link.OriginalURL), code)
+	s.removeUsageLocked(&record.link)
+	delete(s.links, code)
+	delete(s.clicks, code)
+	return nil
+}
+
+// claimOrigin points the reverse entry of originKey at code unless it
+// belongs to another live link
+func (s *MemoryStore) claimOrigin(originKey, code string, expiresAt, now time.Time) {
+	if origin, ok := s.origins[originKey]; !ok || expired(origin.expiresAt, now) || origin.code == code {
+		s.origins[originKey] = memoryCode{code: code, expiresAt: expiresAt}
+	}
+}
+
+// dropOrigin removes the reverse entry of originKey when it points at code
+func (s *MemoryStore) dropOrigin(originKey, code string) {
+	if origin, ok := s.origins[originKey]; ok && origin.code == code {
+		delete(s.origins, originKey)
+	}
+}
+
+// GetByCode gets a short URL by code
+func (s *MemoryStore) GetByCode(ctx context.Context, code string) (*entity.ShortURL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.links[code]
+	if !ok || expired(record.expiresAt, s.now()) {
+		return nil, repository.ErrLinkNotFound
+	}
+	link := record.link
+	return &link, nil
+}
+
+// GetByOriginalURL follows the reverse index to the short URL of owner for the original URL on domain
+func (s *MemoryStore) GetByOriginalURL(ctx context.Context, domain, ownerID, originalURL string) (*entity.ShortURL, error) {
+	s.mu.RLock()
+	origin, ok := s.origins[entity.OriginKey(domain, ownerID, originalURL)]
+	s.mu.RUnlock()
+	if !ok || expired(origin.expiresAt, s.now()) {
+		return nil, repository.ErrLinkNotFound
+	}
+	return s.GetByCode(ctx, origin.code)
+}
+
+// ClaimIdempotencyKey claims an unused key, returning the recorded code when already claimed
+func (s *MemoryStore) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.idempotency[key]; ok && !expired(entry.expiresAt, now) {
+		return entry.code, false, nil
+	}
+	s.idempotency[key] = memoryCode{expiresAt: now.Add(ttl)}
+	return "", true, nil
+}
+
+// CompleteIdempotencyKey records the code created for the key
+func (s *MemoryStore) CompleteIdempotencyKey(ctx context.Context, key, code string, ttl time.Duration) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idempotency[key] = memoryCode{code: code, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes the claim on the key
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.idempotency, key)
+	return nil
+}
+
+// List returns a page of the live links matching the filter
+func (s *MemoryStore) List(ctx context.Context, filter repository.LinkFilter) ([]*entity.ShortURL, error) {
+	s.mu.RLock()
+	now := s.now()
+	var links []*entity.ShortURL
+	for _, record := range s.links {
+		if expired(record.expiresAt, now) {
+			continue
+		}
+		link := record.link
+		if filter.Matches(&link) && filter.IsAfterCursor(repository.CursorOf(&link)) {
+			links = append(links, &link)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		return filter.Less(repository.CursorOf(links[i]), repository.CursorOf(links[j]))
+	})
+	if len(links) > filter.Limit {
+		links = links[:filter.Limit]
+	}
+	return links, nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
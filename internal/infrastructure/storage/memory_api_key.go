@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"sort"
+)
+
+// CreateAPIKey stores a new API key
+func (s *MemoryStore) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiKeys[key.ID]; ok {
+		return fmt.Errorf("api key %s already exists", key.ID)
+	}
+	s.apiKeys[key.ID] = *key
+	s.apiKeyHashes[key.Hash] = key.ID
+	return nil
+}
+
+// UpdateAPIKey overwrites an existing API key
+func (s *MemoryStore) UpdateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.apiKeys[key.ID]
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+	delete(s.apiKeyHashes, previous.Hash)
+	s.apiKeys[key.ID] = *key
+	s.apiKeyHashes[key.Hash] = key.ID
+	return nil
+}
+
+// GetAPIKey gets an API key by ID
+func (s *MemoryStore) GetAPIKey(ctx context.Context, id string) (*entity.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash follows the hash lookup to an API key
+func (s *MemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	s.mu.RLock()
+	id, ok := s.apiKeyHashes[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	return s.GetAPIKey(ctx, id)
+}
+
+// ListAPIKeys returns every API key in creation order
+func (s *MemoryStore) ListAPIKeys(ctx context.Context) ([]*entity.APIKey, error) {
+	s.mu.RLock()
+	keys := make([]*entity.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		key := key
+		keys = append(keys, &key)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
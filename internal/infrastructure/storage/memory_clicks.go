@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/repository"
+)
+
+// ConsumeClick counts one redirect of the link under code unless limit is reached
+func (s *MemoryStore) ConsumeClick(ctx context.Context, code string, limit int) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.links[code]
+	if !ok || expired(record.expiresAt, s.now()) {
+		return 0, false, repository.ErrLinkNotFound
+	}
+	if record.consumed >= limit {
+		return record.consumed, false, nil
+	}
+	record.consumed++
+	s.links[code] = record
+	return record.consumed, true, nil
+}
+
+// ConsumedClicks returns the redirects counted against the click limit of code
+func (s *MemoryStore) ConsumedClicks(ctx context.Context, code string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.links[code]
+	if !ok || expired(record.expiresAt, s.now()) {
+		return 0, nil
+	}
+	return record.consumed, nil
+}
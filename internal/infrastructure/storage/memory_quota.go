@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+)
+
+// memoryDailyCreates is the create counter of an owner, only the latest day is kept
+type memoryDailyCreates struct {
+	day     string
+	creates int
+}
+
+// ReserveDailyCreate counts one create of owner for the day unless limit is reached
+func (s *MemoryStore) ReserveDailyCreate(ctx context.Context, ownerID string, day time.Time, limit int) (int, bool, error) {
+	key := repository.QuotaDay(day)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter := s.dailyCreates[ownerID]
+	if counter.day != key {
+		counter = memoryDailyCreates{day: key}
+	}
+	if counter.creates >= limit {
+		return counter.creates, false, nil
+	}
+	counter.creates++
+	s.dailyCreates[ownerID] = counter
+	return counter.creates, true, nil
+}
+
+// ReleaseDailyCreate gives back a create reserved for the day
+func (s *MemoryStore) ReleaseDailyCreate(ctx context.Context, ownerID string, day time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.dailyCreates[ownerID]
+	if ok && counter.day == repository.QuotaDay(day) && counter.creates > 0 {
+		counter.creates--
+		s.dailyCreates[ownerID] = counter
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+)
+
+// memoryClicks holds the click counters of one link
+type memoryClicks struct {
+	buckets    map[entity.Granularity]map[int64]int64 // granularity -> bucket start -> clicks
+	breakdowns map[string]map[int64]map[string]int64  // dimension -> day start -> value -> clicks
+	visitors   map[int64]hyperLogLog                  // day start -> unique visitors
+}
+
+// RecordClicks increments the counters of a batch of clicks
+func (s *MemoryStore) RecordClicks(ctx context.Context, events []*entity.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		s.recordClick(event)
+	}
+	return nil
+}
+
+// recordClick increments the counters of one click, the caller holds the lock
+func (s *MemoryStore) recordClick(event *entity.ClickEvent) {
+	clicks, ok := s.clicks[event.Code]
+	if !ok {
+		clicks = &memoryClicks{
+			buckets:    make(map[entity.Granularity]map[int64]int64),
+			breakdowns: make(map[string]map[int64]map[string]int64),
+			visitors:   make(map[int64]hyperLogLog),
+		}
+		s.clicks[event.Code] = clicks
+	}
+	for _, granularity := range []entity.Granularity{entity.GranularityMinute, entity.GranularityHour, entity.GranularityDay} {
+		if clicks.buckets[granularity] == nil {
+			clicks.buckets[granularity] = make(map[int64]int64)
+		}
+		clicks.buckets[granularity][granularity.BucketStart(event.OccurredAt).Unix()]++
+	}
+	day := entity.GranularityDay.BucketStart(event.OccurredAt).Unix()
+	for dimension, value := range event.Dimensions() {
+		if clicks.breakdowns[dimension] == nil {
+			clicks.breakdowns[dimension] = make(map[int64]map[string]int64)
+		}
+		if clicks.breakdowns[dimension][day] == nil {
+			clicks.breakdowns[dimension][day] = make(map[string]int64)
+		}
+		clicks.breakdowns[dimension][day][value]++
+	}
+	if event.VisitorID != "" {
+		if clicks.visitors[day] == nil {
+			clicks.visitors[day] = newHyperLogLog()
+		}
+		clicks.visitors[day].add(event.VisitorID)
+	}
+}
+
+// GetClickStats returns the counters of the query range
+func (s *MemoryStore) GetClickStats(ctx context.Context, query repository.StatsQuery) (*entity.ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := &entity.ClickStats{Breakdowns: make(map[string]map[string]int64)}
+	for _, dimension := range []string{entity.DimensionReferrer, entity.DimensionBrowser, entity.DimensionCountry} {
+		stats.Breakdowns[dimension] = make(map[string]int64)
+	}
+	clicks, ok := s.clicks[query.Code]
+	if !ok {
+		stats.Series = query.Series(nil)
+		for _, day := range query.Days() {
+			stats.DailyVisitors = append(stats.DailyVisitors, entity.VisitorBucket{Start: day})
+		}
+		return stats, nil
+	}
+
+	// Series reads the counts by bucket start, so the live map can be handed over
+	stats.Series = query.Series(clicks.buckets[query.Granularity])
+	union := newHyperLogLog()
+	for _, day := range query.Days() {
+		for dimension, days := range clicks.breakdowns {
+			for value, count := range days[day.Unix()] {
+				stats.Breakdowns[dimension][value] += count
+			}
+		}
+		bucket := entity.VisitorBucket{Start: day}
+		if visitors, ok := clicks.visitors[day.Unix()]; ok {
+			bucket.Visitors = visitors.count()
+			union.merge(visitors)
+		}
+		stats.DailyVisitors = append(stats.DailyVisitors, bucket)
+	}
+	stats.UniqueVisitors = union.count()
+	return stats, nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"time"
+)
+
+// memoryUsage counts the active links of a scope, the same scopes as the
+// file store. Links are kept by code with their expiry and in a queue
+// ordered by expiry, so expired links are trimmed when counting instead of
+// scanning every link. Queue entries of links that left the scope or
+// changed expiry are skipped when they come up.
+type memoryUsage struct {
+	expiries map[string]time.Time // zero means the link never expires
+	queue    usageQueue
+}
+
+type usageQueueEntry struct {
+	expiresAt time.Time
+	code      string
+}
+
+// usageQueue is a min-heap of links by expiry
+type usageQueue []usageQueueEntry
+
+func (q usageQueue) Len() int           { return len(q) }
+func (q usageQueue) Less(i, j int) bool { return q[i].expiresAt.Before(q[j].expiresAt) }
+func (q usageQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *usageQueue) Push(x any)        { *q = append(*q, x.(usageQueueEntry)) }
+func (q *usageQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+func (u *memoryUsage) add(code string, expiresAt time.Time) {
+	u.expiries[code] = expiresAt
+	if !expiresAt.IsZero() {
+		heap.Push(&u.queue, usageQueueEntry{expiresAt: expiresAt, code: code})
+	}
+}
+
+// remove stops counting code, compacting the queue once skipped entries
+// outnumber the counted links
+func (u *memoryUsage) remove(code string) {
+	delete(u.expiries, code)
+	if len(u.queue) > 2*len(u.expiries)+64 {
+		u.queue = u.queue[:0]
+		for code, expiresAt := range u.expiries {
+			if !expiresAt.IsZero() {
+				u.queue = append(u.queue, usageQueueEntry{expiresAt: expiresAt, code: code})
+			}
+		}
+		heap.Init(&u.queue)
+	}
+}
+
+// count trims the links expired at now and counts the rest
+func (u *memoryUsage) count(now time.Time) int {
+	for len(u.queue) > 0 && expired(u.queue[0].expiresAt, now) {
+		entry := heap.Pop(&u.queue).(usageQueueEntry)
+		if expiresAt, ok := u.expiries[entry.code]; ok && expiresAt.Equal(entry.expiresAt) {
+			delete(u.expiries, entry.code)
+		}
+	}
+	return len(u.expiries)
+}
+
+// usageExpiry returns when a stored link stops being active, the earlier
+// of its own expiry and the expiry of its record
+func usageExpiry(link *entity.ShortURL, expiresAt time.Time) time.Time {
+	if link.ExpiresAt != nil && (expiresAt.IsZero() || link.ExpiresAt.Before(expiresAt)) {
+		return *link.ExpiresAt
+	}
+	return expiresAt
+}
+
+// addUsageLocked counts the link in its scopes when it is active at now,
+// the caller holds the lock
+func (s *MemoryStore) addUsageLocked(link *entity.ShortURL, expiresAt, now time.Time) {
+	if !link.IsActive(now) {
+		return
+	}
+	for _, scope := range usageScopes(link) {
+		usage, ok := s.usage[scope]
+		if !ok {
+			usage = &memoryUsage{expiries: make(map[string]time.Time)}
+			s.usage[scope] = usage
+		}
+		usage.add(link.Key(), usageExpiry(link, expiresAt))
+	}
+}
+
+// removeUsageLocked stops counting a stored version of a link, the caller
+// holds the lock
+func (s *MemoryStore) removeUsageLocked(link *entity.ShortURL) {
+	for _, scope := range usageScopes(link) {
+		if usage, ok := s.usage[scope]; ok {
+			usage.remove(link.Key())
+			if len(usage.expiries) == 0 {
+				delete(s.usage, scope)
+			}
+		}
+	}
+}
+
+// countUsageLocked counts the active links of a scope at now, the caller
+// holds the lock
+func (s *MemoryStore) countUsageLocked(scope string, now time.Time) int {
+	usage, ok := s.usage[scope]
+	if !ok {
+		return 0
+	}
+	return usage.count(now)
+}
+
+// checkUsageLimitsLocked fails when counting the link, if active, would
+// take its scopes over limits, the caller holds the lock
+func (s *MemoryStore) checkUsageLimitsLocked(link *entity.ShortURL, limits repository.LinkLimits, now time.Time) error {
+	if !link.IsActive(now) {
+		return nil
+	}
+	if limits.Total > 0 && s.countUsageLocked(usageAll, now) >= limits.Total {
+		return repository.ErrTotalLinkLimit
+	}
+	if limits.Owner > 0 && link.OwnerID != "" && s.countUsageLocked(usageOwnerPrefix+link.OwnerID, now) >= limits.Owner {
+		return repository.ErrOwnerLinkLimit
+	}
+	return nil
+}
+
+// Count counts the active short URLs
+func (s *MemoryStore) Count(ctx context.Context) (int, error) {
+	return s.countUsage(usageAll), nil
+}
+
+// CountByOwner counts the active short URLs of an API key
+func (s *MemoryStore) CountByOwner(ctx context.Context, ownerID string) (int, error) {
+	return s.countUsage(usageOwnerPrefix + ownerID), nil
+}
+
+// countUsage trims the expired links of a scope and counts the rest
+func (s *MemoryStore) countUsage(scope string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countUsageLocked(scope, s.now())
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/cache"
+)
+
+// Supported storage drivers
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+	DriverFile   = "file"
+)
+
+// Store is implemented by every storage backend
+type Store interface {
+	repository.LinkRepository
+	repository.IdempotencyRepository
+	repository.StatsRepository
+	repository.APIKeyRepository
+	repository.QuotaRepository
+}
+
+// New creates the storage backend selected by the configuration
+func New(cfg *config.Config) (Store, error) {
+	switch cfg.Storage.Driver {
+	case DriverRedis, "":
+		return cache.NewRedisClient(cfg)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	case DriverFile:
+		return NewBoltStore(cfg.Storage.FilePath)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Storage.Driver)
+	}
+}
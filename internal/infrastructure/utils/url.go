@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// hostProfile converts hosts to their ASCII form as looked up, without the
+// hostname rules that would refuse names such as internal_service
+var hostProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false))
+
+// defaultPorts are dropped from the hosts of their scheme
+var defaultPorts = map[string]string{"http": "80", "https": "443", "ftp": "21"}
+
+// hostSchemes are the hierarchical schemes whose URLs must name a host
+var hostSchemes = []string{"http", "https", "ftp", "ws", "wss"}
+
+// NeedsHost reports whether URLs of scheme must name a host. Opaque forms
+// such as https:evil.example parse without one and are only valid for
+// schemes like mailto:.
+func NeedsHost(scheme string) bool {
+	return slices.Contains(hostSchemes, strings.ToLower(scheme))
+}
+
+// NormalizeURL canonicalizes an absolute URL so that spellings of the same
+// destination share one code: the scheme and host are lowercased, the host
+// converted to punycode and its default port dropped. With sortQuery the
+// query parameters are sorted too, which re-encodes the query.
+func NormalizeURL(raw string, sortQuery bool) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme == "" {
+		return "", errors.New("url has no scheme")
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Host != "" {
+		if parsed.Host, err = NormalizeHost(parsed.Host, parsed.Scheme); err != nil {
+			return "", err
+		}
+	}
+	if sortQuery && parsed.RawQuery != "" {
+		parsed.RawQuery = parsed.Query().Encode()
+	}
+	return parsed.String(), nil
+}
+
+// NormalizeHost lowercases host, converts it to punycode and drops the
+// default port of scheme
+func NormalizeHost(host, scheme string) (string, error) {
+	hostname, port := host, ""
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		hostname, port = host[:i], host[i+1:]
+	}
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	if strings.HasPrefix(hostname, "[") {
+		// IPv6 literals have no international form
+		hostname = strings.ToLower(hostname)
+	} else if net.ParseIP(hostname) == nil {
+		ascii, err := hostProfile.ToASCII(strings.TrimSuffix(hostname, "."))
+		if err != nil {
+			return "", err
+		}
+		hostname = ascii
+	}
+	if port == "" {
+		return hostname, nil
+	}
+	return net.JoinHostPort(strings.Trim(hostname, "[]"), port), nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/domain/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController handles the admin HTTP requests for API keys
+type APIKeyController struct {
+	apiKeyUseCase usecase.APIKeyUseCase
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(apiKeyUseCase usecase.APIKeyUseCase) *APIKeyController {
+	return &APIKeyController{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// RegisterRoutes registers the admin routes for API keys
+func (c *APIKeyController) RegisterRoutes(router *gin.Engine, auth gin.HandlerFunc, limits RouteLimits) {
+	admin := router.Group("/api/admin/keys", chain(auth, limits.API)...)
+	admin.POST("", c.IssueAPIKey)
+	admin.GET("", c.ListAPIKeys)
+	admin.POST("/:id/rotate", c.RotateAPIKey)
+	admin.PUT("/:id/quota", c.UpdateQuota)
+	admin.DELETE("/:id", c.RevokeAPIKey)
+}
+
+// IssueAPIKey issues a new API key
+// @Summary      Issue API key
+// @Description  Issues an API key for a team. The secret is only returned in this response.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.IssueAPIKeyRequest  true  "API key"
+// @Success      201  {object}  dto.APIKeyResponse
+// @Failure      400  "Bad Request - Invalid input"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - Admin key required"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/keys [post]
+func (c *APIKeyController) IssueAPIKey(ctx *gin.Context) {
+	var request dto.IssueAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.apiKeyUseCase.IssueAPIKey(ctx, &request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// ListAPIKeys lists API keys
+// @Summary      List API keys
+// @Description  Lists every API key, revoked ones included, without their secrets
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   dto.APIKeyResponse
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - Admin key required"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/keys [get]
+func (c *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	result, err := c.apiKeyUseCase.ListAPIKeys(ctx)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RotateAPIKey replaces the secret of an API key
+// @Summary      Rotate API key
+// @Description  Replaces the secret of an API key, the previous secret stops working immediately. The key keeps its ID and links.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "API key id"
+// @Success      200  {object}  dto.APIKeyResponse
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - Admin key required"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - API key has been revoked"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/keys/{id}/rotate [post]
+func (c *APIKeyController) RotateAPIKey(ctx *gin.Context) {
+	result, err := c.apiKeyUseCase.RotateAPIKey(ctx, ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UpdateQuota changes the quotas of an API key
+// @Summary      Update API key quota
+// @Description  Sets the active links and daily creates a key may use. 0 uses the configured default and -1 lifts the limit. Existing links are kept when a quota is lowered.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "API key id"
+// @Param        request  body      dto.UpdateQuotaRequest  true  "Quotas"
+// @Success      200  {object}  dto.APIKeyResponse
+// @Failure      400  "Bad Request - Invalid quota"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - Admin key required"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - API key has been revoked"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/keys/{id}/quota [put]
+func (c *APIKeyController) UpdateQuota(ctx *gin.Context) {
+	var request dto.UpdateQuotaRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.apiKeyUseCase.UpdateQuota(ctx, ctx.Param("id"), &request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RevokeAPIKey revokes an API key
+// @Summary      Revoke API key
+// @Description  Revokes an API key for good. Its links stay in place and can still be managed by admins.
+// @Tags         admin
+// @Param        id   path      string  true  "API key id"
+// @Success      204  "No Content"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - Admin key required"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - API key has already been revoked"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/admin/keys/{id} [delete]
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	if err := c.apiKeyUseCase.RevokeAPIKey(ctx, ctx.Param("id")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// comingSoonPage tells browsers visiting a link before its activation
+// window when it goes live
+var comingSoonPage = template.Must(template.New("coming-soon").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Coming soon</title>
+</head>
+<body>
+<h1>Coming soon</h1>
+<p>{{.Message}}</p>
+<p>Live from <time datetime="{{.NotBefore}}">{{.NotBefore}}</time></p>
+</body>
+</html>
+`))
+
+// respondComingSoon answers a visit before the activation window of a link
+// with 503 Service Unavailable and a Retry-After of when it goes live,
+// serving the coming soon page to browsers
+func respondComingSoon(ctx *gin.Context, err *usecase.ScheduledLinkError) {
+	notBefore := err.NotBefore.UTC().Format(time.RFC3339)
+	retryAfter := int(time.Until(err.NotBefore).Seconds()) + 1
+	ctx.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	ctx.Header("Cache-Control", "no-store")
+	if !strings.Contains(ctx.GetHeader("Accept"), "text/html") {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "not_before": notBefore})
+		return
+	}
+	var page bytes.Buffer
+	data := struct{ Message, NotBefore string }{err.Message, notBefore}
+	if renderErr := comingSoonPage.Execute(&page, data); renderErr != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "not_before": notBefore})
+		return
+	}
+	ctx.Data(http.StatusServiceUnavailable, "text/html; charset=utf-8", page.Bytes())
+}
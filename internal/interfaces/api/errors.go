@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/domain/dto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatuses maps use case errors to their HTTP status codes
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{usecase.ErrLinkNotFound, http.StatusNotFound},
+	{usecase.ErrLinkExpired, http.StatusGone},
+	{usecase.ErrLinkDeleted, http.StatusGone},
+	{usecase.ErrLinkExhausted, http.StatusGone},
+	{usecase.ErrLinkEnded, http.StatusGone},
+	{usecase.ErrLinkNotYetActive, http.StatusServiceUnavailable},
+	{usecase.ErrLinkNotDeleted, http.StatusConflict},
+	{usecase.ErrInvalidOriginalURL, http.StatusBadRequest},
+	{usecase.ErrInvalidExpiration, http.StatusBadRequest},
+	{usecase.ErrInvalidListQuery, http.StatusBadRequest},
+	{usecase.ErrInvalidStatsQuery, http.StatusBadRequest},
+	{usecase.ErrInvalidAlias, http.StatusBadRequest},
+	{usecase.ErrAliasReserved, http.StatusBadRequest},
+	{usecase.ErrAliasTaken, http.StatusConflict},
+	{usecase.ErrIdempotencyKeyInProgress, http.StatusConflict},
+	{usecase.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity},
+	{usecase.ErrUnauthorized, http.StatusUnauthorized},
+	{usecase.ErrForbidden, http.StatusForbidden},
+	{usecase.ErrAPIKeyNotFound, http.StatusNotFound},
+	{usecase.ErrAPIKeyRevoked, http.StatusGone},
+	{usecase.ErrInvalidQuota, http.StatusBadRequest},
+	{usecase.ErrUnknownDomain, http.StatusBadRequest},
+	{usecase.ErrInvalidRedirectStatus, http.StatusBadRequest},
+	{usecase.ErrInvalidQueryConflict, http.StatusBadRequest},
+	{usecase.ErrInvalidTemplate, http.StatusBadRequest},
+	{usecase.ErrMissingTemplateArgs, http.StatusBadRequest},
+	{usecase.ErrDestinationBlocked, http.StatusUnprocessableEntity},
+	{usecase.ErrShortLinkDestination, http.StatusBadRequest},
+	{usecase.ErrRedirectLoop, http.StatusLoopDetected},
+	{usecase.ErrInvalidPassword, http.StatusBadRequest},
+	{usecase.ErrPasswordRequired, http.StatusUnauthorized},
+	{usecase.ErrWrongPassword, http.StatusUnauthorized},
+	{usecase.ErrInvalidMaxClicks, http.StatusBadRequest},
+	{usecase.ErrInvalidSchedule, http.StatusBadRequest},
+	{usecase.ErrInvalidRoutingRule, http.StatusBadRequest},
+}
+
+// respondError writes err with the status matching its use case error,
+// falling back to 500 Internal Server Error
+func respondError(ctx *gin.Context, err error) {
+	var quotaErr *usecase.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		respondQuotaExceeded(ctx, quotaErr)
+		return
+	}
+	status := http.StatusInternalServerError
+	for _, candidate := range errorStatuses {
+		if errors.Is(err, candidate.err) {
+			status = candidate.status
+			break
+		}
+	}
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
+
+// respondQuotaExceeded rejects a request over a quota. Daily quotas answer
+// 429 Too Many Requests with Retry-After, the others 403 Forbidden since
+// retrying does not help until links are deleted.
+func respondQuotaExceeded(ctx *gin.Context, err *usecase.QuotaExceededError) {
+	status := http.StatusForbidden
+	header := quotaHeader(err.Quota)
+	ctx.Header(header+"-Limit", strconv.Itoa(err.Limit))
+	ctx.Header(header+"-Remaining", "0")
+	body := gin.H{
+		"error":     err.Error(),
+		"quota":     err.Quota,
+		"limit":     err.Limit,
+		"remaining": 0,
+	}
+	if err.ResetAt != nil {
+		status = http.StatusTooManyRequests
+		retryAfter := int(time.Until(*err.ResetAt).Seconds()) + 1
+		ctx.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		ctx.Header("X-Quota-Reset", strconv.FormatInt(err.ResetAt.Unix(), 10))
+		body["reset_at"] = err.ResetAt.UTC().Format(time.RFC3339)
+	}
+	ctx.JSON(status, body)
+}
+
+// setQuotaHeaders reports the quotas left after a create
+func setQuotaHeaders(ctx *gin.Context, quota *dto.QuotaStatus) {
+	if quota == nil {
+		return
+	}
+	if quota.LinksLimit > 0 {
+		header := quotaHeader(usecase.QuotaLinks)
+		ctx.Header(header+"-Limit", strconv.Itoa(quota.LinksLimit))
+		ctx.Header(header+"-Remaining", strconv.Itoa(quota.LinksRemaining))
+	}
+	if quota.DailyCreatesLimit > 0 {
+		header := quotaHeader(usecase.QuotaDailyCreates)
+		ctx.Header(header+"-Limit", strconv.Itoa(quota.DailyCreatesLimit))
+		ctx.Header(header+"-Remaining", strconv.Itoa(quota.DailyCreatesRemaining))
+		ctx.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt, 10))
+	}
+}
+
+// quotaHeader names the headers of a quota, daily_creates becomes X-Quota-Daily-Creates
+func quotaHeader(quota string) string {
+	return http.CanonicalHeaderKey("x-quota-" + strings.ReplaceAll(quota, "_", "-"))
+}
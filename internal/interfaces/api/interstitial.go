@@ -0,0 +1,39 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// interstitialPage warns visitors of a link whose destination is blocked.
+// The destination is shown as text only, so the page never links to it.
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: blocked destination</title>
+</head>
+<body>
+<h1>This link has been blocked</h1>
+<p>The page this short link points to may be unsafe, so you were not redirected.</p>
+<p>Reason: {{.Reason}}</p>
+<p>Destination: <code>{{.Destination}}</code></p>
+</body>
+</html>
+`))
+
+// respondBlocked serves the interstitial warning page of a blocked visit
+func respondBlocked(ctx *gin.Context, err *usecase.BlockedDestinationError) {
+	var page bytes.Buffer
+	if renderErr := interstitialPage.Execute(&page, err); renderErr != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusForbidden, "text/html; charset=utf-8", page.Bytes())
+}
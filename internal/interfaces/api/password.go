@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// PasswordHeader carries the password of a protected link for API clients
+	PasswordHeader = "X-Link-Password"
+	// passwordField is the password field of the form served to browsers
+	passwordField = "link_password"
+	// accessCookie keeps the access token of a link, scoped to its path
+	accessCookie = "link_access"
+)
+
+// passwordPage asks browsers for the password of a protected link. The
+// form posts back to the visited URL, query included.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Password required</title>
+</head>
+<body>
+<h1>This link is password protected</h1>
+{{if .Wrong}}<p role="alert">Wrong password, please try again.</p>
+{{end}}<form method="post">
+<label for="password">Password</label>
+<input id="password" name="` + passwordField + `" type="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// submittedPassword returns the password a visit gives, from the header or
+// the password form, and whether it came from the form
+func submittedPassword(ctx *gin.Context) (string, bool) {
+	if password := ctx.GetHeader(PasswordHeader); password != "" {
+		return password, false
+	}
+	if ctx.Request.Method != http.MethodPost {
+		return "", false
+	}
+	password := ctx.PostForm(passwordField)
+	return password, password != ""
+}
+
+// PasswordGuessKey keys the password guesses of a visit by the link it
+// visits, so that guesses are limited per link whoever makes them. Visits
+// giving no password are not limited.
+func PasswordGuessKey(ctx *gin.Context) string {
+	if password, _ := submittedPassword(ctx); password == "" {
+		return ""
+	}
+	return entity.LinkKey(usecase.DomainFrom(ctx.Request.Context()), ctx.Param("id"))
+}
+
+// wantsPasswordForm reports whether a visit lacking the password of a link
+// comes from a browser, which gets the password form instead of an error
+func wantsPasswordForm(ctx *gin.Context) bool {
+	return ctx.GetHeader(PasswordHeader) == "" && strings.Contains(ctx.GetHeader("Accept"), "text/html")
+}
+
+// respondPasswordForm serves the password form, telling when the password
+// just given was wrong
+func respondPasswordForm(ctx *gin.Context, wrong bool) {
+	var page bytes.Buffer
+	if err := passwordPage.Execute(&page, struct{ Wrong bool }{wrong}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusUnauthorized, "text/html; charset=utf-8", page.Bytes())
+}
+
+// setAccessCookie keeps the access token of a visit that gave the password.
+// The cookie is scoped to the path of the link, such as /shortlinks/abc,
+// which its extra paths share but other links do not.
+func setAccessCookie(ctx *gin.Context, result *dto.RedirectResponse) {
+	prefix, _, _ := strings.Cut(ctx.FullPath(), "/:id")
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(accessCookie, result.AccessToken, int(time.Until(result.AccessExpiresAt).Seconds()),
+		prefix+"/"+ctx.Param("id"), "", secure, true)
+}
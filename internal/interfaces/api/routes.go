@@ -0,0 +1,23 @@
+package api
+
+import "github.com/gin-gonic/gin"
+
+// RouteLimits holds the rate limiting middlewares of the route groups, a
+// nil middleware leaves its group unlimited
+type RouteLimits struct {
+	Create   gin.HandlerFunc // POST /api/shortlinks
+	Redirect gin.HandlerFunc // public redirects
+	Password gin.HandlerFunc // password guesses per protected link
+	API      gin.HandlerFunc // every other /api route
+}
+
+// chain drops the nil middlewares of disabled features
+func chain(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+	chained := make([]gin.HandlerFunc, 0, len(handlers))
+	for _, handler := range handlers {
+		if handler != nil {
+			chained = append(chained, handler)
+		}
+	}
+	return chained
+}
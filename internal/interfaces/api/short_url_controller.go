@@ -1,9 +1,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
 	"shorter-rest-api/internal/domain/dto"
+	"slices"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,35 +15,101 @@ import (
 // UserController handles HTTP requests for users
 type ShortUrlController struct {
 	shortUrlUseCase usecase.ShortUrlUseCase
+	statsUseCase    usecase.StatsUseCase
+	redirectPrefix  string
 }
 
-// NewUserController creates a new user controller
-func NewShortUrlController(shortUrlUseCase usecase.ShortUrlUseCase) *ShortUrlController {
+// NewUserController creates a new user controller, serving redirects
+// under redirectPrefix, the root when empty
+func NewShortUrlController(shortUrlUseCase usecase.ShortUrlUseCase, statsUseCase usecase.StatsUseCase, redirectPrefix string) *ShortUrlController {
 	return &ShortUrlController{
 		shortUrlUseCase: shortUrlUseCase,
+		statsUseCase:    statsUseCase,
+		redirectPrefix:  redirectPrefix,
 	}
 }
 
 // RegisterRoutes registers the routes for the user controller
-func (c *ShortUrlController) RegisterRoutes(router *gin.Engine) {
+func (c *ShortUrlController) RegisterRoutes(router *gin.Engine, auth gin.HandlerFunc, limits RouteLimits) {
 
-	// Register protected  routes
+	// Register protected  routes. Rate limits run after authentication
+	// so that they apply per API key.
+	create := router.Group("/api/shortlinks", chain(auth, limits.Create, c.scopeDomain)...)
+	create.POST("", c.CreateShortUrl)
+	protected := router.Group("/api/shortlinks", chain(auth, limits.API, c.scopeDomain)...)
+	protected.GET("", c.ListShortUrls)
+	protected.GET("/:id", c.GetShortByCode)
+	protected.PATCH("/:id", c.UpdateShortUrl)
+	protected.DELETE("/:id", c.DeleteShortUrl)
+	protected.POST("/:id/restore", c.RestoreShortUrl)
+	protected.PUT("/:id/expiration", c.UpdateExpiration)
+	protected.GET("/:id/stats", c.GetClickStats)
 
-	router.GET("/api/shortlinks/:id", c.GetShortByCode)
-	router.POST("/api/shortlinks", c.CreateShortUrl)
-	router.GET("/shortlinks/:id", c.Redirect)
+	// Register public routes. Links handed out under the default prefix
+	// keep redirecting when another one is configured.
+	redirect := chain(limits.Redirect, c.hostDomain, limits.Password, c.Redirect)
+	prefixes := []string{config.DefaultRedirectPrefix}
+	if c.redirectPrefix != config.DefaultRedirectPrefix {
+		prefixes = append(prefixes, c.redirectPrefix)
+	}
+	for _, prefix := range prefixes {
+		router.Match(redirectMethods, prefix+"/:id", redirect...)
+		router.Match(redirectMethods, prefix+"/:id/*path", redirect...)
+	}
+}
+
+// redirectMethods are the methods short links answer. Besides GET, links
+// redirecting with 307 or 308 forward the method and body of API calls.
+var redirectMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// scopeDomain addresses the links of the domain query parameter, the
+// default domain when it is missing
+func (c *ShortUrlController) scopeDomain(ctx *gin.Context) {
+	domain, err := c.shortUrlUseCase.ResolveDomain(ctx.Query("domain"))
+	if err != nil {
+		respondError(ctx, err)
+		ctx.Abort()
+		return
+	}
+	ctx.Request = ctx.Request.WithContext(usecase.WithDomain(ctx.Request.Context(), domain))
+	ctx.Next()
+}
+
+// hostDomain addresses the links of the domain serving the request Host,
+// as the same code can exist on several domains
+func (c *ShortUrlController) hostDomain(ctx *gin.Context) {
+	domain := c.shortUrlUseCase.DomainForHost(ctx.Request.Host)
+	ctx.Request = ctx.Request.WithContext(usecase.WithDomain(ctx.Request.Context(), domain))
+	ctx.Next()
 }
 
+// previewParams describe the visit GetShortByCode previews
+var previewParams = []string{"path", "query", "user_agent", "accept_language", "country"}
+
 // GetShortByCode gets a shorturl by ID
 // @Summary      Get shorturl by ID
-// @Description  Retrieves a specific shorturl by its ID
+// @Description  Retrieves a specific shorturl by its ID. With path, query, user_agent, accept_language or country, preview is the destination a visit with them redirects to, such as the expansion of a template link or the destination a routing rule picks.
 // @Tags         shorturl
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "short id"
+// @Param        id      path   int     true   "short id"
+// @Param        domain  query  string  false  "Short link domain, the default domain when empty"
+// @Param        path    query  string  false  "Path of the previewed visit after the code, such as PROJ-123"
+// @Param        query   query  string  false  "Query of the previewed visit, such as utm_source=x"
+// @Param        user_agent       query  string  false  "User-Agent of the previewed visit"
+// @Param        accept_language  query  string  false  "Accept-Language of the previewed visit"
+// @Param        country          query  string  false  "ISO 3166-1 alpha-2 country of the previewed visit"
 // @Success      200  {object}  dto.GetShortUrlResponse
-// @Failure      400  "id is required"
+// @Failure      400  "Bad Request - id is required or missing template arguments"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - Short URL has expired or been deleted"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
 // @Failure 	 500 "Internal Server Error"
+// @Security     ApiKeyAuth
 // @Router       /api/shortlinks/{id} [get]
 func (c *ShortUrlController) GetShortByCode(ctx *gin.Context) {
 
@@ -49,9 +119,58 @@ func (c *ShortUrlController) GetShortByCode(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.shortUrlUseCase.GetShortUrlByCode(ctx, id)
+	query := ctx.Request.URL.Query()
+	var result *dto.GetShortUrlResponse
+	var err error
+	if slices.ContainsFunc(previewParams, query.Has) {
+		result, err = c.shortUrlUseCase.PreviewShortUrl(ctx, id, &dto.RedirectRequest{
+			Path:           query.Get("path"),
+			Query:          query.Get("query"),
+			UserAgent:      query.Get("user_agent"),
+			AcceptLanguage: query.Get("accept_language"),
+			Country:        query.Get("country"),
+		})
+	} else {
+		result, err = c.shortUrlUseCase.GetShortUrlByCode(ctx, id)
+	}
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListShortUrls lists shorturls
+// @Summary      List shorturls
+// @Description  Lists shorturls page by page in creation order, optionally filtered by destination host, tag and creation date range
+// @Tags         shorturl
+// @Produce      json
+// @Param        cursor        query     string  false  "next_cursor of the previous page"
+// @Param        limit         query     int     false  "Page size (default 20, max 100)"
+// @Param        sort          query     string  false  "created_at or -created_at (default)"
+// @Param        host          query     string  false  "Destination host"
+// @Param        tag           query     string  false  "Tag"
+// @Param        created_from  query     string  false  "Created at or after (RFC 3339 or date)"
+// @Param        created_to    query     string  false  "Created at or before (RFC 3339 or date)"
+// @Param        domain        query     string  false  "Short link domain, the default domain when empty"
+// @Success      200  {object}  dto.ListResponse
+// @Failure      400  "Bad Request - Invalid query"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks [get]
+func (c *ShortUrlController) ListShortUrls(ctx *gin.Context) {
+	var request dto.ListRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.shortUrlUseCase.ListShortUrls(ctx, &request)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
@@ -60,16 +179,29 @@ func (c *ShortUrlController) GetShortByCode(ctx *gin.Context) {
 
 // Redirect shorturl by ID
 // @Summary      Redirect to original URL
-// @Description  Redirects to the original URL for the given short code
+// @Description  Redirects to the original URL for the given short code on the domain of the request Host. Also served under REDIRECT_PREFIX, such as /{id} when it is "/". Links forwarding paths also answer /{id}/extra/path, and links forwarding queries merge the visited query into the destination.
 // @Tags         shorturl
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "short id"
+// @Param        id               path    int     true   "short id"
+// @Param        X-Link-Password  header  string  false  "Password of a protected link, browsers get a password form instead"
 // @Success      200  {object}  dto.GetShortUrlResponse
 // @Failure      400  "Bad Request - Invalid input"
-// @Failure      302 "Found - Redirects to original URL"
+// @Failure      302 "Found - Redirects to original URL, or 301, 307 or 308 as chosen for the link"
+// @Failure      401  "Unauthorized - The link is password protected and no or a wrong password was given, browsers get a password form"
+// @Failure      403  "Forbidden - The destination is blocked, an HTML warning page is served"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - Short URL has expired, been deleted, served all the redirects of its click limit or ended its activation window"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      503  "Service Unavailable - The activation window of the link has not started and there is no fallback URL, browsers get a coming soon page, see Retry-After"
+// @Failure      508  "Loop Detected - The link leads through too many chained short links"
 // @Failure 	 500 "Internal Server Error"
 // @Router       /shortlinks/{id} [get]
+// @Router       /shortlinks/{id} [head]
+// @Router       /shortlinks/{id} [post]
+// @Router       /shortlinks/{id} [put]
+// @Router       /shortlinks/{id} [patch]
+// @Router       /shortlinks/{id} [delete]
 func (c *ShortUrlController) Redirect(ctx *gin.Context) {
 
 	id := ctx.Param("id")
@@ -78,26 +210,125 @@ func (c *ShortUrlController) Redirect(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.shortUrlUseCase.GetShortUrlByCode(ctx, id)
+	password, fromForm := submittedPassword(ctx)
+	accessToken, _ := ctx.Cookie(accessCookie)
+	result, err := c.shortUrlUseCase.Redirect(ctx, id, &dto.RedirectRequest{
+		Path:           ctx.Param("path"),
+		Query:          ctx.Request.URL.RawQuery,
+		Password:       password,
+		AccessToken:    accessToken,
+		UserAgent:      ctx.Request.UserAgent(),
+		AcceptLanguage: ctx.GetHeader("Accept-Language"),
+		ClientIP:       ctx.ClientIP(),
+		Probe:          ctx.Request.Method == http.MethodHead,
+	})
+	var blocked *usecase.BlockedDestinationError
+	if errors.As(err, &blocked) {
+		respondBlocked(ctx, blocked)
+		return
+	}
+	var scheduled *usecase.ScheduledLinkError
+	if errors.As(err, &scheduled) {
+		respondComingSoon(ctx, scheduled)
+		return
+	}
+	if (errors.Is(err, usecase.ErrPasswordRequired) || errors.Is(err, usecase.ErrWrongPassword)) && wantsPasswordForm(ctx) {
+		respondPasswordForm(ctx, errors.Is(err, usecase.ErrWrongPassword))
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(ctx, err)
+		return
+	}
+
+	status := result.Status
+	// Routed locations depend on the visitor and the time, even permanent
+	// redirects of routed links must not be cached
+	if result.Routed {
+		ctx.Header("Cache-Control", "no-store")
+	}
+	if result.AccessToken != "" {
+		setAccessCookie(ctx, result)
+		// A 307 or 308 would post the password on to the destination
+		if fromForm {
+			status = http.StatusSeeOther
+		}
+	}
+
+	// Visits outside the activation window and HEAD requests are not
+	// clicks of the link
+	if result.Fallback || ctx.Request.Method == http.MethodHead {
+		ctx.Redirect(status, result.Location)
 		return
 	}
 
-	ctx.Redirect(http.StatusFound, result.OriginalUrl)
+	// Recording is queued so analytics never delays the redirect
+	c.statsUseCase.TrackClick(result.ID, &dto.ClickRequest{
+		Domain:    usecase.DomainFrom(ctx.Request.Context()),
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Referrer:  ctx.Request.Referer(),
+	})
+	ctx.Redirect(status, result.Location)
+}
+
+// GetClickStats gets the click analytics of a shorturl
+// @Summary      Get shorturl click stats
+// @Description  Returns clicks per minute, hour or day over a time range, with referrer host, browser and country breakdowns of the days the range touches. Buckets are in UTC.
+// @Tags         shorturl
+// @Produce      json
+// @Param        id           path   string  true   "short id"
+// @Param        domain       query  string  false  "Short link domain, the default domain when empty"
+// @Param        from         query  string  false  "Range start, RFC 3339 timestamp or date"
+// @Param        to           query  string  false  "Range end, RFC 3339 timestamp or date (default now)"
+// @Param        granularity  query  string  false  "minute (up to 24h), hour (up to 31 days) or day (default, up to 366 days)"
+// @Success      200  {object}  dto.StatsResponse
+// @Failure      400  "Bad Request - Invalid range or granularity"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - Short URL has been deleted"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks/{id}/stats [get]
+func (c *ShortUrlController) GetClickStats(ctx *gin.Context) {
+	var request dto.StatsRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.statsUseCase.GetClickStats(ctx, ctx.Param("id"), &request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
 }
 
 // CreateShortUrl creates a new shorturl
 // @Summary      Create shorturl
-// @Description  Creates a new shorturl
+// @Description  Creates a new shorturl using the optional alias as its code. Without an alias, returns the live short link already assigned to the same URL unless force_new is set.
 // @Tags         shorturl
 // @Accept       json
 // @Produce      json
-// @Param        request  body      dto.CreateRequest  true  "URL object"
+// @Param        request          body      dto.CreateRequest  true   "URL object"
+// @Param        Idempotency-Key  header    string             false  "Retries with the same key never mint a second code"
+// @Success      200  {object}  dto.CreateResponse  "Existing short link"
 // @Success      201  {object}  dto.CreateResponse
-// @Failure      400  "Bad Request - Invalid input"
-// @Failure      409  "Conflict - Short URL already exists"
+// @Failure      400  "Bad Request - Invalid input or alias, or a destination that is a short link which cannot be collapsed"
+// @Failure      409  "Conflict - Alias already taken or a request with the same Idempotency-Key is in progress"
+// @Failure      422  "Unprocessable Entity - Idempotency-Key reused for a different URL, or the destination is blocked"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      403  "Forbidden - Link quota of the API key or of the service used up"
+// @Failure      429  "Too Many Requests - Rate limit exceeded or daily create quota used up, see Retry-After"
 // @Failure      500  "Internal Server Error"
+// @Header       201  {integer}  X-Quota-Links-Remaining          "Active links the API key may still create"
+// @Header       201  {integer}  X-Quota-Daily-Creates-Remaining  "Creates left for the API key today (UTC)"
+// @Header       201  {integer}  X-Quota-Reset                    "Unix time the daily creates reset"
+// @Security     ApiKeyAuth
 // @Router       /api/shortlinks [post]
 func (c *ShortUrlController) CreateShortUrl(ctx *gin.Context) {
 	var shortUrl dto.CreateRequest
@@ -105,22 +336,149 @@ func (c *ShortUrlController) CreateShortUrl(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Validate duplicate short URL
-	isDuplicate, err := c.shortUrlUseCase.ValidateDuplicateShortUrl(shortUrl.OriginalUrl)
+	shortUrl.IdempotencyKey = ctx.GetHeader("Idempotency-Key")
+
+	result, created, err := c.shortUrlUseCase.CreateShortUrl(ctx, &shortUrl)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
+		return
+	}
+
+	setQuotaHeaders(ctx, result.Quota)
+	if !created {
+		ctx.JSON(http.StatusOK, result)
 		return
 	}
-	if isDuplicate {
-		ctx.JSON(http.StatusConflict, gin.H{"error": "Short URL already exists"})
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// UpdateExpiration changes the lifetime of a shorturl
+// @Summary      Update shorturl expiration
+// @Description  Extends or shortens the lifetime of a shorturl. Expired links can be revived within the retention window.
+// @Tags         shorturl
+// @Accept       json
+// @Produce      json
+// @Param        id       path   string                       true   "short id"
+// @Param        domain   query  string                       false  "Short link domain, the default domain when empty"
+// @Param        request  body   dto.UpdateExpirationRequest  true   "New expiration"
+// @Success      200  {object}  dto.GetShortUrlResponse
+// @Failure      400  "Bad Request - Invalid expiration"
+// @Failure      404  "Not Found"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks/{id}/expiration [put]
+func (c *ShortUrlController) UpdateExpiration(ctx *gin.Context) {
+	var request dto.UpdateExpirationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	result, err := c.shortUrlUseCase.CreateShortUrl(ctx, &shortUrl)
+	result, err := c.shortUrlUseCase.UpdateExpiration(ctx, ctx.Param("id"), &request)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, result)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UpdateShortUrl changes the destination and metadata of a shorturl
+// @Summary      Update shorturl
+// @Description  Changes the destination URL, title or tags of a shorturl. Omitted fields are left unchanged.
+// @Tags         shorturl
+// @Accept       json
+// @Produce      json
+// @Param        id       path   string             true   "short id"
+// @Param        domain   query  string             false  "Short link domain, the default domain when empty"
+// @Param        request  body   dto.UpdateRequest  true   "Fields to change"
+// @Success      200  {object}  dto.GetShortUrlResponse
+// @Failure      400  "Bad Request - Invalid input"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - Short URL has expired or been deleted"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      422  "Unprocessable Entity - The destination is blocked"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks/{id} [patch]
+func (c *ShortUrlController) UpdateShortUrl(ctx *gin.Context) {
+	var request dto.UpdateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := c.shortUrlUseCase.UpdateShortUrl(ctx, ctx.Param("id"), &request)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeleteShortUrl deletes a shorturl
+// @Summary      Delete shorturl
+// @Description  Soft deletes a shorturl so it can be restored within the retention window, or removes it for good with permanent=true
+// @Tags         shorturl
+// @Param        id         path   string  true   "short id"
+// @Param        domain     query  string  false  "Short link domain, the default domain when empty"
+// @Param        permanent  query  bool    false  "Delete permanently"
+// @Success      204  "No Content"
+// @Failure      400  "Bad Request - Invalid input"
+// @Failure      404  "Not Found"
+// @Failure      410  "Gone - Short URL is already deleted"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks/{id} [delete]
+func (c *ShortUrlController) DeleteShortUrl(ctx *gin.Context) {
+	permanent := false
+	if value := ctx.Query("permanent"); value != "" {
+		var err error
+		if permanent, err = strconv.ParseBool(value); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "permanent must be a boolean"})
+			return
+		}
+	}
+
+	if err := c.shortUrlUseCase.DeleteShortUrl(ctx, ctx.Param("id"), permanent); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RestoreShortUrl restores a soft deleted shorturl
+// @Summary      Restore shorturl
+// @Description  Restores a soft deleted shorturl within the retention window
+// @Tags         shorturl
+// @Produce      json
+// @Param        id      path   string  true   "short id"
+// @Param        domain  query  string  false  "Short link domain, the default domain when empty"
+// @Success      200  {object}  dto.GetShortUrlResponse
+// @Failure      404  "Not Found - Unknown or past the retention window"
+// @Failure      409  "Conflict - Short URL is not deleted"
+// @Failure      401  "Unauthorized - Missing or invalid API key"
+// @Failure      429  "Too Many Requests - Rate limit exceeded, see Retry-After"
+// @Failure      403  "Forbidden - The link belongs to another API key"
+// @Failure      500  "Internal Server Error"
+// @Security     ApiKeyAuth
+// @Router       /api/shortlinks/{id}/restore [post]
+func (c *ShortUrlController) RestoreShortUrl(ctx *gin.Context) {
+	result, err := c.shortUrlUseCase.RestoreShortUrl(ctx, ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key when the Authorization header is not used
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth creates a middleware requiring a valid API key, sent either as
+// "Authorization: Bearer <key>" or in the X-API-Key header. The key is put
+// in the request context for the use cases to check ownership. When
+// authentication is disabled every request passes anonymously.
+func APIKeyAuth(apiKeyUseCase usecase.APIKeyUseCase, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		secret := c.GetHeader(APIKeyHeader)
+		if authorization := c.GetHeader("Authorization"); authorization != "" {
+			scheme, token, ok := strings.Cut(authorization, " ")
+			if ok && strings.EqualFold(scheme, "Bearer") {
+				secret = token
+			}
+		}
+
+		key, err := apiKeyUseCase.Authenticate(c, secret)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, usecase.ErrUnauthorized) {
+				status = http.StatusUnauthorized
+				c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Request = c.Request.WithContext(usecase.WithCaller(c.Request.Context(), key))
+		c.Next()
+	}
+}
+
+// RequireAdmin creates a middleware letting only admin keys through. It
+// runs after APIKeyAuth, anonymous requests pass like they do there when
+// authentication is disabled.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if caller := usecase.CallerFrom(c.Request.Context()); caller != nil && !caller.Admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": usecase.ErrForbidden.Error()})
+			return
+		}
+		c.Next()
+	}
+}
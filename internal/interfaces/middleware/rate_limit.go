@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/infrastructure/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit creates a middleware limiting the requests of a route group.
+// Authenticated requests are limited per API key, the others per client IP.
+// Every response carries the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers; rejected requests answer
+// 429 Too Many Requests with Retry-After. It returns nil when the limit is
+// disabled. Requests pass when the limiter fails.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return RateLimitBy(limiter, group, limit, func(c *gin.Context) string {
+		if caller := usecase.CallerFrom(c.Request.Context()); caller != nil {
+			return "key:" + caller.ID
+		}
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// RateLimitBy creates a middleware limiting requests like RateLimit, by
+// the key key returns for them. Requests it returns no key for pass.
+func RateLimitBy(limiter ratelimit.Limiter, group string, limit ratelimit.Limit, key func(c *gin.Context) string) gin.HandlerFunc {
+	if !limit.Enabled() {
+		return nil
+	}
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		client := key(c)
+		if client == "" {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c, group+":"+client, limit)
+		if err != nil {
+			log.Printf("Failed to apply rate limit: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		c.Header("RateLimit-Policy", policy)
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, retry later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds a delay up to whole seconds, as the headers expect
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
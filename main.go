@@ -8,6 +8,9 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Embedded IANA zones for the daily windows of routing rules, the
+	// alpine image ships none
+	_ "time/tzdata"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -15,8 +18,13 @@ import (
 	_ "shorter-rest-api/docs"
 	"shorter-rest-api/internal/application/usecase"
 	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/infrastructure/analytics"
 	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/infrastructure/policy"
+	"shorter-rest-api/internal/infrastructure/ratelimit"
+	"shorter-rest-api/internal/infrastructure/storage"
 	"shorter-rest-api/internal/interfaces/api"
+	"shorter-rest-api/internal/interfaces/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +34,9 @@ import (
 // @description     		   Swagger Shorter API Documentation.
 // @host            		   localhost:8080
 // @BasePath       			   /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in                         header
+// @name                       X-API-Key
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -33,32 +44,85 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Set up database connection
-	inMemDB, err := cache.NewRedisClient(cfg)
+	// Set up storage
+	store, err := storage.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	defer store.Close()
+
+	// Set up click analytics
+	geoLocator, err := analytics.NewGeoLocator(cfg.Analytics.GeoIPPath)
+	if err != nil {
+		log.Fatalf("Failed to load GeoIP database: %v", err)
+	}
+	defer geoLocator.Close()
+	clickRecorder := analytics.NewRecorder(store, geoLocator, analytics.RecorderOptions{
+		QueueSize:     cfg.Analytics.QueueSize,
+		Workers:       cfg.Analytics.Workers,
+		BatchSize:     cfg.Analytics.BatchSize,
+		FlushInterval: time.Duration(cfg.Analytics.FlushInterval) * time.Millisecond,
+		Salt:          cfg.Analytics.VisitorSalt,
+	})
+
+	// Set up the destination policy
+	destinationPolicy, err := policy.New(policy.Options{
+		AllowDomains:   cfg.Policy.AllowDomains,
+		DenyDomains:    cfg.Policy.DenyDomains,
+		BlocklistPath:  cfg.Policy.BlocklistPath,
+		ReloadInterval: time.Duration(cfg.Policy.BlocklistReload) * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load destination policy: %v", err)
+	}
+	defer destinationPolicy.Close()
 
 	// Create use cases
-	shorterUseCase := usecase.NewShortUrlUseCase(cfg, inMemDB)
+	shorterUseCase := usecase.NewShortUrlUseCase(cfg, store, store, store, destinationPolicy, geoLocator)
+	statsUseCase := usecase.NewStatsUseCase(store, store, clickRecorder)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(cfg, store)
 
-	// Create Gin router
+	// Create Gin router. Context lookups fall back to the request context,
+	// which carries the authenticated API key.
 	router := gin.New()
+	router.ContextWithFallback = true
+	// Client IPs key the rate limits, only trusted proxies may set them
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Register swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Register controllers
-	shorterController := api.NewShortUrlController(shorterUseCase)
+	shorterController := api.NewShortUrlController(shorterUseCase, statsUseCase, cfg.Server.RedirectPrefix)
+	apiKeyController := api.NewAPIKeyController(apiKeyUseCase)
 
 	// Register routes
-	shorterController.RegisterRoutes(router)
+	if !cfg.Auth.Enabled {
+		log.Println("Warning: API key authentication is disabled")
+	} else if cfg.Auth.AdminKey == "" {
+		log.Println("Warning: ADMIN_API_KEY is not set, only existing API keys can authenticate")
+	}
+	auth := middleware.APIKeyAuth(apiKeyUseCase, cfg.Auth.Enabled)
+	limits := newRouteLimits(cfg, store)
+	shorterController.RegisterRoutes(router, auth, limits)
+	apiKeyController.RegisterRoutes(router, auth, limits)
 
 	// Add health check endpoint
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 
+	// Add click pipeline metrics endpoint, served to admins only
+	admin := router.Group("/metrics", auth, middleware.RequireAdmin())
+	if limits.API != nil {
+		admin.Use(limits.API)
+	}
+	admin.GET("/clicks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, clickRecorder.Metrics())
+	})
+
 	// Create server
 	port := cfg.Server.Port
 	if port == "" {
@@ -90,8 +154,41 @@ func main() {
 
 	// Shut down server
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush the click events still queued before the storage is closed,
+	// even when the server did not shut down cleanly
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFlush()
+	if err := clickRecorder.Close(flushCtx); err != nil {
+		log.Printf("Failed to flush click events: %v", err)
+	}
+	metrics := clickRecorder.Metrics()
+	log.Printf("Click events recorded: %d, dropped: %d, failed: %d", metrics.Recorded, metrics.Dropped, metrics.Failed)
+
 	log.Println("Server exiting")
 }
+
+// newRouteLimits creates the rate limits of the route groups. They are
+// shared through Redis when it is the storage, with a per node fallback
+// while Redis is unreachable, and kept in memory otherwise.
+func newRouteLimits(cfg *config.Config, store storage.Store) api.RouteLimits {
+	if !cfg.RateLimit.Enabled {
+		log.Println("Warning: rate limiting is disabled")
+		return api.RouteLimits{}
+	}
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if redisClient, ok := store.(*cache.RedisClient); ok {
+		limiter = ratelimit.WithFallback(ratelimit.NewRedisLimiter(redisClient.Conn), limiter)
+	}
+	limit := func(requests, windowSeconds int) ratelimit.Limit {
+		return ratelimit.Limit{Requests: requests, Window: time.Duration(windowSeconds) * time.Second}
+	}
+	return api.RouteLimits{
+		Create:   middleware.RateLimit(limiter, "create", limit(cfg.RateLimit.CreateLimit, cfg.RateLimit.CreateWindow)),
+		Redirect: middleware.RateLimit(limiter, "redirect", limit(cfg.RateLimit.RedirectLimit, cfg.RateLimit.RedirectWindow)),
+		API:      middleware.RateLimit(limiter, "api", limit(cfg.RateLimit.APILimit, cfg.RateLimit.APIWindow)),
+		Password: middleware.RateLimitBy(limiter, "password", limit(cfg.RateLimit.PasswordLimit, cfg.RateLimit.PasswordWindow), api.PasswordGuessKey),
+	}
+}
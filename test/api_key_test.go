@@ -0,0 +1,215 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"shorter-rest-api/internal/interfaces/middleware"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiKeyRepositories(t *testing.T) map[string]repository.APIKeyRepository {
+	boltStore, err := storage.NewBoltStore(filepath.Join(t.TempDir(), "keys.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boltStore.Close() })
+
+	return map[string]repository.APIKeyRepository{
+		"redis":  &cache.RedisClient{Conn: newTestRedisPool(t)},
+		"memory": storage.NewMemoryStore(),
+		"file":   boltStore,
+	}
+}
+
+func TestAPIKeyRepository_RotateMovesHash(t *testing.T) {
+	for name, repo := range apiKeyRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+			first := &entity.APIKey{ID: "k1", Name: "growth", Hash: "hash-1", CreatedAt: base}
+			second := &entity.APIKey{ID: "k2", Name: "support", Hash: "hash-2", CreatedAt: base.Add(time.Hour)}
+			require.NoError(t, repo.CreateAPIKey(ctx, second))
+			require.NoError(t, repo.CreateAPIKey(ctx, first))
+			assert.Error(t, repo.CreateAPIKey(ctx, first))
+
+			first.Hash = "hash-3"
+			require.NoError(t, repo.UpdateAPIKey(ctx, first))
+			_, err := repo.GetAPIKeyByHash(ctx, "hash-1")
+			assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+			key, err := repo.GetAPIKeyByHash(ctx, "hash-3")
+			require.NoError(t, err)
+			assert.Equal(t, "growth", key.Name)
+
+			keys, err := repo.ListAPIKeys(ctx)
+			require.NoError(t, err)
+			require.Len(t, keys, 2)
+			assert.Equal(t, "k1", keys[0].ID)
+
+			assert.ErrorIs(t, repo.UpdateAPIKey(ctx, &entity.APIKey{ID: "missing", Hash: "x"}), repository.ErrAPIKeyNotFound)
+		})
+	}
+}
+
+func newTestAPIKeyUseCase(store *storage.MemoryStore) usecase.APIKeyUseCase {
+	cfg := &config.Config{}
+	cfg.Auth.Enabled = true
+	cfg.Auth.AdminKey = "bootstrap-secret"
+	return usecase.NewAPIKeyUseCase(cfg, store)
+}
+
+func TestAPIKeyUseCase_Lifecycle(t *testing.T) {
+	store := storage.NewMemoryStore()
+	keys := newTestAPIKeyUseCase(store)
+
+	admin, err := keys.Authenticate(context.Background(), "bootstrap-secret")
+	require.NoError(t, err)
+	adminCtx := usecase.WithCaller(context.Background(), admin)
+
+	issued, err := keys.IssueAPIKey(adminCtx, &dto.IssueAPIKeyRequest{Name: "growth"})
+	require.NoError(t, err)
+	require.NotEmpty(t, issued.Secret)
+	assert.True(t, strings.HasPrefix(issued.Secret, issued.Prefix))
+
+	team, err := keys.Authenticate(context.Background(), issued.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, team.ID)
+
+	// Only admins manage keys
+	_, err = keys.IssueAPIKey(usecase.WithCaller(context.Background(), team), &dto.IssueAPIKeyRequest{Name: "sneaky"})
+	assert.ErrorIs(t, err, usecase.ErrForbidden)
+
+	rotated, err := keys.RotateAPIKey(adminCtx, issued.ID)
+	require.NoError(t, err)
+	_, err = keys.Authenticate(context.Background(), issued.Secret)
+	assert.ErrorIs(t, err, usecase.ErrUnauthorized)
+	_, err = keys.Authenticate(context.Background(), rotated.Secret)
+	require.NoError(t, err)
+
+	listed, err := keys.ListAPIKeys(adminCtx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Secret)
+
+	require.NoError(t, keys.RevokeAPIKey(adminCtx, issued.ID))
+	_, err = keys.Authenticate(context.Background(), rotated.Secret)
+	assert.ErrorIs(t, err, usecase.ErrUnauthorized)
+	assert.ErrorIs(t, keys.RevokeAPIKey(adminCtx, issued.ID), usecase.ErrAPIKeyRevoked)
+}
+
+func TestShortUrlUseCase_OwnershipIsEnforced(t *testing.T) {
+	uc := newTestUseCase()
+	growth := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "growth"})
+	support := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "support"})
+	admin := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "admin", Admin: true})
+
+	created, _, err := uc.CreateShortUrl(growth, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+
+	title := "hijacked"
+	_, err = uc.UpdateShortUrl(support, created.ID, &dto.UpdateRequest{Title: &title})
+	assert.ErrorIs(t, err, usecase.ErrForbidden)
+	assert.ErrorIs(t, uc.DeleteShortUrl(support, created.ID, false), usecase.ErrForbidden)
+
+	// Another team shortening the same URL gets its own link
+	other, created2, err := uc.CreateShortUrl(support, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+	assert.True(t, created2)
+	assert.NotEqual(t, created.ID, other.ID)
+
+	// and each team keeps getting its own link back
+	for caller, want := range map[context.Context]string{growth: created.ID, support: other.ID} {
+		again, minted, err := uc.CreateShortUrl(caller, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+		require.NoError(t, err)
+		assert.False(t, minted)
+		assert.Equal(t, want, again.ID)
+	}
+
+	page, err := uc.ListShortUrls(support, &dto.ListRequest{})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "support", page.Items[0].OwnerID)
+
+	page, err = uc.ListShortUrls(admin, &dto.ListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+
+	_, err = uc.UpdateShortUrl(admin, created.ID, &dto.UpdateRequest{Title: &title})
+	require.NoError(t, err)
+	require.NoError(t, uc.DeleteShortUrl(growth, created.ID, false))
+}
+
+func TestAPIKeyAuth_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	keys := newTestAPIKeyUseCase(store)
+	cfg := &config.Config{MaximumShortUrlCount: 100}
+	links := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(links, usecase.NewStatsUseCase(store, store, nil), config.DefaultRedirectPrefix).RegisterRoutes(router, middleware.APIKeyAuth(keys, true), api.RouteLimits{})
+
+	request := func(method, path, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/shortlinks", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/shortlinks", "", "wrong").Code)
+
+	response := request(http.MethodPost, "/api/shortlinks", `{"original_url":"https://example.com"}`, "bootstrap-secret")
+	require.Equal(t, http.StatusCreated, response.Code, response.Body.String())
+	var created dto.CreateResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+	link, err := store.GetByCode(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", link.OwnerID)
+}
+
+func TestRequireAdmin_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := newTestAPIKeyUseCase(storage.NewMemoryStore())
+	admin, err := keys.Authenticate(context.Background(), "bootstrap-secret")
+	require.NoError(t, err)
+	issued, err := keys.IssueAPIKey(usecase.WithCaller(context.Background(), admin), &dto.IssueAPIKeyRequest{Name: "growth"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/metrics/clicks", middleware.APIKeyAuth(keys, true), middleware.RequireAdmin(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics/clicks", nil)
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(""))
+	assert.Equal(t, http.StatusForbidden, request(issued.Secret))
+	assert.Equal(t, http.StatusOK, request("bootstrap-secret"))
+}
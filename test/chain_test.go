@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShortUrl_CollapsesShortLinkDestinations(t *testing.T) {
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, nil)
+	ctx := context.Background()
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/final", Alias: "first"})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/docs", Alias: "docs", Domain: "brand.co", ForwardPath: true, ForwardQuery: true})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://jira.example/browse/{1}", Alias: "jira"})
+	require.NoError(t, err)
+
+	for destination, want := range map[string]string{
+		"https://sho.rt/shortlinks/first":                     "https://example.com/final",
+		"HTTPS://SHO.RT:443/shortlinks/first":                 "https://example.com/final",
+		"http://brand.co/shortlinks/docs/guide/intro?lang=en": "https://example.com/docs/guide/intro?lang=en",
+		"https://sho.rt/shortlinks/jira/PROJ-1":               "https://jira.example/browse/PROJ-1",
+		// Other pages of our hosts are not links
+		"https://sho.rt/api/shortlinks/first": "https://sho.rt/api/shortlinks/first",
+		"https://sho.rt/about":                "https://sho.rt/about",
+	} {
+		created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: destination, ForceNew: true})
+		require.NoError(t, err, destination)
+		link, err := uc.GetShortUrlByCode(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, want, link.OriginalUrl, destination)
+	}
+
+	for _, destination := range []string{
+		"https://sho.rt/shortlinks/missing",
+		"https://brand.co/shortlinks/first",
+		"https://sho.rt/shortlinks/jira",
+		"https://sho.rt/shortlinks/{1}",
+	} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: destination})
+		assert.ErrorIs(t, err, usecase.ErrShortLinkDestination, destination)
+	}
+
+	// Pointing a link at itself keeps a loop from ever being stored
+	self := "https://sho.rt/shortlinks/first"
+	link, err := uc.UpdateShortUrl(ctx, "first", &dto.UpdateRequest{OriginalUrl: &self})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/final", link.OriginalUrl)
+}
+
+func TestCreateShortUrl_RejectsShortLinkDestinations(t *testing.T) {
+	cfg := newDomainTestConfig()
+	cfg.URL.RejectShortLinks = true
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/final", Alias: "first"})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://sho.rt/shortlinks/first"})
+	assert.ErrorIs(t, err, usecase.ErrShortLinkDestination)
+}
+
+func TestRedirect_FollowsStoredChainsUpToMaxDepth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newDomainTestConfig()
+	cfg.URL.MaxChainDepth = 3
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+
+	// Chains stored before destinations were collapsed
+	for code, destination := range map[string]string{
+		"one":   "https://sho.rt/shortlinks/two",
+		"two":   "https://brand.co/shortlinks/three",
+		"four":  "https://sho.rt/shortlinks/one",
+		"tick":  "https://sho.rt/shortlinks/tock",
+		"tock":  "https://sho.rt/shortlinks/tick",
+		"stale": "https://sho.rt/shortlinks/gone",
+	} {
+		require.NoError(t, store.Create(ctx, &entity.ShortURL{Code: code, OriginalURL: destination, CreatedAt: time.Now()}, 0, repository.LinkLimits{}))
+	}
+	require.NoError(t, store.Create(ctx, &entity.ShortURL{Code: "three", Domain: "brand.co", OriginalURL: "https://example.com/end", CreatedAt: time.Now()}, 0, repository.LinkLimits{}))
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(code string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/shortlinks/"+code, nil))
+		return recorder
+	}
+
+	response := serve("one")
+	require.Equal(t, http.StatusFound, response.Code)
+	assert.Equal(t, "https://example.com/end", response.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusLoopDetected, serve("four").Code)
+	assert.Equal(t, http.StatusLoopDetected, serve("tick").Code)
+	assert.Equal(t, http.StatusNotFound, serve("stale").Code)
+}
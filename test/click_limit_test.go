@@ -0,0 +1,172 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkRepository_ConsumeClickNeverExceedsLimit(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			link := &entity.ShortURL{Code: "limited", OriginalURL: "https://example.com", CreatedAt: time.Now(), MaxClicks: 5}
+			require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+
+			var counted atomic.Int32
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, ok, err := repo.ConsumeClick(ctx, "limited", link.MaxClicks)
+					assert.NoError(t, err)
+					if ok {
+						counted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			assert.EqualValues(t, 5, counted.Load())
+			consumed, err := repo.ConsumedClicks(ctx, "limited")
+			require.NoError(t, err)
+			assert.Equal(t, 5, consumed)
+
+			// Updates keep the count, a raised limit lets more redirects through
+			require.NoError(t, repo.Update(ctx, link, time.Hour))
+			used, ok, err := repo.ConsumeClick(ctx, "limited", 6)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, 6, used)
+
+			// A new link under the same code starts from zero
+			require.NoError(t, repo.Delete(ctx, "limited"))
+			require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+			consumed, err = repo.ConsumedClicks(ctx, "limited")
+			require.NoError(t, err)
+			assert.Zero(t, consumed)
+
+			_, _, err = repo.ConsumeClick(ctx, "missing", 1)
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+		})
+	}
+}
+
+func TestShortUrlUseCase_ClickLimitedLinks(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/once", MaxClicks: -1})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMaxClicks)
+
+	open, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/once"})
+	require.NoError(t, err)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/once", MaxClicks: 1})
+	require.NoError(t, err)
+	// Limited links are never reused for other requests
+	assert.NotEqual(t, open.ID, created.ID)
+	again, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/once"})
+	require.NoError(t, err)
+	assert.Equal(t, open.ID, again.ID)
+
+	link, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, link.MaxClicks)
+	require.NotNil(t, link.RemainingClicks)
+	assert.Equal(t, 1, *link.RemainingClicks)
+
+	// Previews do not use up the click
+	_, err = uc.PreviewShortUrl(ctx, created.ID, &dto.RedirectRequest{})
+	require.NoError(t, err)
+	result, err := uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/once", result.Location)
+
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrLinkExhausted)
+	link, err = uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, *link.RemainingClicks)
+
+	// Raising or removing the limit opens the link again
+	maxClicks := 2
+	_, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{MaxClicks: &maxClicks})
+	require.NoError(t, err)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	require.NoError(t, err)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrLinkExhausted)
+	maxClicks = 0
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{MaxClicks: &maxClicks})
+	require.NoError(t, err)
+	assert.Zero(t, link.MaxClicks)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.NoError(t, err)
+}
+
+func TestRedirect_ExhaustedLinkAnswersGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newDomainTestConfig()
+	cfg.ExhaustedLinkMessage = "this invitation was already used"
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/invite", Alias: "invite", MaxClicks: 1})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/vault", Alias: "vault", Password: "hunter22", MaxClicks: 1})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	clicks := &clickLog{}
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, clicks), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	head := func(code string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodHead, "/shortlinks/"+code, nil))
+		return recorder.Code
+	}
+	serve := func(code, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/shortlinks/"+code, nil)
+		if password != "" {
+			req.Header.Set(api.PasswordHeader, password)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Link previewers checking the link with HEAD leave the click to the visitor
+	assert.Equal(t, http.StatusFound, head("invite"))
+	assert.Equal(t, http.StatusFound, head("invite"))
+	assert.Empty(t, clicks.events)
+	link, err := uc.GetShortUrlByCode(ctx, "invite")
+	require.NoError(t, err)
+	assert.Equal(t, 1, *link.RemainingClicks)
+
+	assert.Equal(t, http.StatusFound, serve("invite", "").Code)
+	assert.Len(t, clicks.events, 1)
+	assert.Equal(t, http.StatusGone, head("invite"))
+	response := serve("invite", "")
+	assert.Equal(t, http.StatusGone, response.Code)
+	assert.Contains(t, response.Body.String(), "this invitation was already used")
+
+	// Wrong passwords do not use up the click
+	assert.Equal(t, http.StatusUnauthorized, serve("vault", "wrong-one").Code)
+	assert.Equal(t, http.StatusFound, serve("vault", "hunter22").Code)
+	assert.Equal(t, http.StatusGone, serve("vault", "hunter22").Code)
+}
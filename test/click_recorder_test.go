@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/analytics"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder is a stats repository remembering the size of each batch,
+// optionally holding every write until release is closed
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches []int
+	release chan struct{}
+}
+
+func (b *batchRecorder) RecordClicks(ctx context.Context, events []*entity.ClickEvent) error {
+	if b.release != nil {
+		<-b.release
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, len(events))
+	return nil
+}
+
+func (b *batchRecorder) GetClickStats(ctx context.Context, query repository.StatsQuery) (*entity.ClickStats, error) {
+	return &entity.ClickStats{}, nil
+}
+
+func (b *batchRecorder) batchSizes() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]int(nil), b.batches...)
+}
+
+func newTestRecorder(t *testing.T, stats repository.StatsRepository, options analytics.RecorderOptions) *analytics.Recorder {
+	geo, err := analytics.NewGeoLocator("")
+	require.NoError(t, err)
+	return analytics.NewRecorder(stats, geo, options)
+}
+
+func TestRecorder_FlushesFullBatchesAndAfterInterval(t *testing.T) {
+	stats := &batchRecorder{}
+	recorder := newTestRecorder(t, stats, analytics.RecorderOptions{QueueSize: 100, Workers: 1, BatchSize: 3, FlushInterval: 50 * time.Millisecond})
+
+	for i := 0; i < 7; i++ {
+		require.True(t, recorder.Record(&entity.ClickEvent{Code: "abc", OccurredAt: time.Now()}))
+	}
+	assert.Eventually(t, func() bool { return len(stats.batchSizes()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{3, 3, 1}, stats.batchSizes())
+
+	require.NoError(t, recorder.Close(context.Background()))
+	metrics := recorder.Metrics()
+	assert.Equal(t, uint64(7), metrics.Enqueued)
+	assert.Equal(t, uint64(7), metrics.Recorded)
+	assert.Equal(t, uint64(3), metrics.Batches)
+}
+
+func TestRecorder_DropsWhenQueueIsFullAndFlushesOnClose(t *testing.T) {
+	stats := &batchRecorder{release: make(chan struct{})}
+	recorder := newTestRecorder(t, stats, analytics.RecorderOptions{QueueSize: 2, Workers: 1, BatchSize: 1, FlushInterval: time.Hour})
+
+	// The worker holds the first event while storage is stuck, the next two fill the queue
+	require.True(t, recorder.Record(&entity.ClickEvent{Code: "abc"}))
+	assert.Eventually(t, func() bool { return recorder.Metrics().QueueDepth == 0 }, time.Second, time.Millisecond)
+	require.True(t, recorder.Record(&entity.ClickEvent{Code: "abc"}))
+	require.True(t, recorder.Record(&entity.ClickEvent{Code: "abc"}))
+	assert.False(t, recorder.Record(&entity.ClickEvent{Code: "abc"}))
+	assert.Equal(t, uint64(1), recorder.Metrics().Dropped)
+
+	// Closing waits for the backlog, or gives up with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, recorder.Close(ctx), context.DeadlineExceeded)
+
+	close(stats.release)
+	require.NoError(t, recorder.Close(context.Background()))
+	assert.Equal(t, uint64(3), recorder.Metrics().Recorded)
+	assert.False(t, recorder.Record(&entity.ClickEvent{Code: "abc"}))
+}
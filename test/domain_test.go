@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDomainTestConfig() *config.Config {
+	cfg := &config.Config{MaximumShortUrlCount: 100}
+	cfg.Alias.Charset = "abcdefghijklmnopqrstuvwxyz0123456789-"
+	cfg.Alias.MinLength = 3
+	cfg.Alias.MaxLength = 20
+	cfg.Server.RedirectPrefix = config.DefaultRedirectPrefix
+	cfg.Server.PublicBaseURL = "https://sho.rt"
+	cfg.Server.ShortDomains = []string{"https://brand.co", "http://go.example"}
+	return cfg
+}
+
+// clickLog keeps the click events of a test in memory
+type clickLog struct{ events []*entity.ClickEvent }
+
+func (l *clickLog) Record(event *entity.ClickEvent) bool {
+	l.events = append(l.events, event)
+	return true
+}
+
+func TestShortUrlUseCase_BrandedDomains(t *testing.T) {
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, nil)
+	ctx := context.Background()
+
+	def, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/a", Alias: "docs"})
+	require.NoError(t, err)
+	assert.Empty(t, def.Domain)
+	assert.Equal(t, "https://sho.rt/shortlinks/docs", def.ShortUrl)
+
+	// The same alias is free on another domain
+	brand, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/b", Alias: "docs", Domain: "Brand.co"})
+	require.NoError(t, err)
+	assert.Equal(t, "brand.co", brand.Domain)
+	assert.Equal(t, "https://brand.co/shortlinks/docs", brand.ShortUrl)
+
+	// Deduplication is per domain
+	reused, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/a", Domain: "brand.co"})
+	require.NoError(t, err)
+	assert.NotEqual(t, "docs", reused.ID)
+	again, _, err := uc.CreateShortUrl(usecase.WithDomain(ctx, "brand.co"), &dto.CreateRequest{OriginalUrl: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, reused.ID, again.ID)
+
+	link, err := uc.GetShortUrlByCode(usecase.WithDomain(ctx, "brand.co"), "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", link.OriginalUrl)
+	link, err = uc.GetShortUrlByCode(ctx, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", link.OriginalUrl)
+
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/c", Domain: "unknown.example"})
+	assert.ErrorIs(t, err, usecase.ErrUnknownDomain)
+}
+
+func TestRedirect_ResolvesDomainFromHost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, nil)
+	ctx := context.Background()
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/default", Alias: "docs"})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/brand", Alias: "docs", Domain: "brand.co"})
+	require.NoError(t, err)
+
+	clicks := &clickLog{}
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, clicks), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(method, host, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Host = host
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for host, want := range map[string]string{
+		"brand.co":       "https://example.com/brand",
+		"BRAND.CO:443":   "https://example.com/brand",
+		"sho.rt":         "https://example.com/default",
+		"other.example":  "https://example.com/default",
+		"localhost:8080": "https://example.com/default",
+	} {
+		response := serve(http.MethodGet, host, "/shortlinks/docs")
+		require.Equal(t, http.StatusFound, response.Code, host)
+		assert.Equal(t, want, response.Header().Get("Location"), host)
+	}
+	// Clicks are counted for the link of the domain
+	branded := 0
+	for _, event := range clicks.events {
+		if event.Code == entity.LinkKey("brand.co", "docs") {
+			branded++
+		}
+	}
+	assert.Len(t, clicks.events, 5)
+	assert.Equal(t, 2, branded)
+
+	response := serve(http.MethodGet, "sho.rt", "/api/shortlinks/docs?domain=brand.co")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	assert.True(t, strings.Contains(response.Body.String(), `"short_url":"https://brand.co/shortlinks/docs"`), response.Body.String())
+
+	response = serve(http.MethodGet, "sho.rt", "/api/shortlinks/docs?domain=unknown.example")
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
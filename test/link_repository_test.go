@@ -0,0 +1,328 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestRedisPool(t *testing.T) *redis.Pool {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		MaxIdle: 10,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func linkRepositories(t *testing.T) map[string]repository.LinkRepository {
+	boltStore, err := storage.NewBoltStore(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boltStore.Close() })
+
+	return map[string]repository.LinkRepository{
+		"redis":  &cache.RedisClient{Conn: newTestRedisPool(t)},
+		"memory": storage.NewMemoryStore(),
+		"file":   boltStore,
+	}
+}
+
+func TestLinkRepository_SaveAndGet(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			link := &entity.ShortURL{Code: "abc123", OriginalURL: "https://example.com", CreatedAt: time.Now().UTC()}
+			require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+
+			result, err := repo.GetByCode(ctx, "abc123")
+			require.NoError(t, err)
+			assert.Equal(t, link.OriginalURL, result.OriginalURL)
+
+			byOrigin, err := repo.GetByOriginalURL(ctx, "", "", "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "abc123", byOrigin.Code)
+
+			count, err := repo.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestLinkRepository_NotFound(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := repo.GetByCode(ctx, "missing")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+
+			_, err = repo.GetByOriginalURL(ctx, "", "", "https://missing.com")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+		})
+	}
+}
+
+func TestLinkRepository_CreateRejectsTakenCode(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			first := &entity.ShortURL{Code: "taken", OriginalURL: "https://first.com"}
+			second := &entity.ShortURL{Code: "taken", OriginalURL: "https://second.com"}
+			require.NoError(t, repo.Create(ctx, first, 0, repository.LinkLimits{}))
+
+			err := repo.Create(ctx, second, 0, repository.LinkLimits{})
+			assert.ErrorIs(t, err, repository.ErrCodeAlreadyExists)
+
+			// The losing create must not leave a reverse entry behind
+			_, err = repo.GetByOriginalURL(ctx, "", "", "https://second.com")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+
+			result, err := repo.GetByCode(ctx, "taken")
+			require.NoError(t, err)
+			assert.Equal(t, "https://first.com", result.OriginalURL)
+		})
+	}
+}
+
+func TestLinkRepository_CreateKeepsReverseEntryOfLiveLink(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "first", OriginalURL: "https://example.com"}, time.Hour, repository.LinkLimits{}))
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "second", OriginalURL: "https://example.com"}, time.Hour, repository.LinkLimits{}))
+
+			byOrigin, err := repo.GetByOriginalURL(ctx, "", "", "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "first", byOrigin.Code)
+
+			// A free entry is claimed by the next link created for the destination
+			require.NoError(t, repo.Delete(ctx, "first"))
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "third", OriginalURL: "https://example.com"}, time.Hour, repository.LinkLimits{}))
+			byOrigin, err = repo.GetByOriginalURL(ctx, "", "", "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "third", byOrigin.Code)
+
+			// Every API key has its own entry for the destination
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "owned", OriginalURL: "https://example.com", OwnerID: "growth"}, time.Hour, repository.LinkLimits{}))
+			byOrigin, err = repo.GetByOriginalURL(ctx, "", "growth", "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, "owned", byOrigin.Code)
+			_, err = repo.GetByOriginalURL(ctx, "", "support", "https://example.com")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+		})
+	}
+}
+
+func TestLinkRepository_ConcurrentCreateSameCode(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var wg sync.WaitGroup
+			var created int32
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					link := &entity.ShortURL{Code: "race", OriginalURL: fmt.Sprintf("https://example.com/%d", i)}
+					if repo.Create(ctx, link, time.Minute, repository.LinkLimits{}) == nil {
+						atomic.AddInt32(&created, 1)
+					}
+				}(i)
+			}
+			wg.Wait()
+			assert.Equal(t, int32(1), created)
+		})
+	}
+}
+
+func TestLinkRepository_UpdateMovesReverseEntry(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			link := &entity.ShortURL{Code: "moved", OriginalURL: "https://old.com"}
+			require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+
+			link.OriginalURL = "https://new.com"
+			require.NoError(t, repo.Update(ctx, link, time.Hour))
+
+			_, err := repo.GetByOriginalURL(ctx, "", "", "https://old.com")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+			byOrigin, err := repo.GetByOriginalURL(ctx, "", "", "https://new.com")
+			require.NoError(t, err)
+			assert.Equal(t, "moved", byOrigin.Code)
+
+			// Soft deleted links give up their reverse entry
+			deletedAt := time.Now()
+			link.DeletedAt = &deletedAt
+			require.NoError(t, repo.Update(ctx, link, time.Hour))
+			_, err = repo.GetByOriginalURL(ctx, "", "", "https://new.com")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+
+			require.NoError(t, repo.Delete(ctx, "moved"))
+			_, err = repo.GetByCode(ctx, "moved")
+			assert.ErrorIs(t, err, repository.ErrLinkNotFound)
+			assert.ErrorIs(t, repo.Update(ctx, link, time.Hour), repository.ErrLinkNotFound)
+		})
+	}
+}
+
+func TestLinkRepository_ConcurrentUpdatesKeepReverseEntries(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "raced", OriginalURL: "https://u.com/0"}, time.Hour, repository.LinkLimits{}))
+
+			var wg sync.WaitGroup
+			for i := 1; i <= 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 5; j++ {
+						link := &entity.ShortURL{Code: "raced", OriginalURL: fmt.Sprintf("https://u.com/%d", i)}
+						assert.NoError(t, repo.Update(ctx, link, time.Hour))
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Only the destination the link ended with points at it
+			stored, err := repo.GetByCode(ctx, "raced")
+			require.NoError(t, err)
+			for i := 0; i <= 4; i++ {
+				originalURL := fmt.Sprintf("https://u.com/%d", i)
+				byOrigin, err := repo.GetByOriginalURL(ctx, "", "", originalURL)
+				if originalURL == stored.OriginalURL {
+					require.NoError(t, err)
+					assert.Equal(t, originalURL, byOrigin.OriginalURL)
+				} else {
+					assert.ErrorIs(t, err, repository.ErrLinkNotFound, originalURL)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkRepository_ListPagesAndFilters(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+			for i := 0; i < 7; i++ {
+				link := &entity.ShortURL{
+					Code:        fmt.Sprintf("code%d", i),
+					OriginalURL: fmt.Sprintf("https://%s/%d", []string{"a.com", "b.com"}[i%2], i),
+					CreatedAt:   base.Add(time.Duration(i) * time.Hour),
+				}
+				if i%3 == 0 {
+					link.Tags = []string{"sale"}
+				}
+				if i < 2 {
+					link.OwnerID = "growth"
+				}
+				require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+			}
+
+			// Page newest first, two at a time
+			var codes []string
+			filter := repository.LinkFilter{Descending: true, Limit: 2}
+			for {
+				page, err := repo.List(ctx, filter)
+				require.NoError(t, err)
+				for _, link := range page {
+					codes = append(codes, link.Code)
+				}
+				if len(page) < filter.Limit {
+					break
+				}
+				cursor := repository.CursorOf(page[len(page)-1])
+				filter.After = &cursor
+			}
+			assert.Equal(t, []string{"code6", "code5", "code4", "code3", "code2", "code1", "code0"}, codes)
+
+			page, err := repo.List(ctx, repository.LinkFilter{Host: "a.com", Limit: 10})
+			require.NoError(t, err)
+			assert.Len(t, page, 4)
+
+			page, err = repo.List(ctx, repository.LinkFilter{Tag: "sale", Limit: 10})
+			require.NoError(t, err)
+			assert.Len(t, page, 3)
+
+			page, err = repo.List(ctx, repository.LinkFilter{OwnerID: "growth", Limit: 10})
+			require.NoError(t, err)
+			assert.Len(t, page, 2)
+
+			page, err = repo.List(ctx, repository.LinkFilter{Host: "c.com", Limit: 10})
+			require.NoError(t, err)
+			assert.Empty(t, page)
+
+			from, to := base.Add(2*time.Hour), base.Add(4*time.Hour)
+			page, err = repo.List(ctx, repository.LinkFilter{CreatedFrom: &from, CreatedTo: &to, Descending: true, Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, page, 3)
+			assert.Equal(t, "code4", page[0].Code)
+		})
+	}
+}
+
+func TestBoltStore_ListsReusedCodesOnce(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.db")
+	store, err := storage.NewBoltStore(path)
+	require.NoError(t, err)
+	created := time.Now().UTC()
+	expiring := &entity.ShortURL{Code: "reused", OriginalURL: "https://a.com/old", Tags: []string{"sale"}, CreatedAt: created}
+	require.NoError(t, store.Create(ctx, expiring, 50*time.Millisecond, repository.LinkLimits{}))
+	time.Sleep(100 * time.Millisecond)
+
+	// The new link under the code of the expired one replaces its index entries
+	link := &entity.ShortURL{Code: "reused", OriginalURL: "https://a.com/new", CreatedAt: created.Add(time.Second)}
+	require.NoError(t, store.Create(ctx, link, 0, repository.LinkLimits{}))
+	page, err := store.List(ctx, repository.LinkFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "https://a.com/new", page[0].OriginalURL)
+	page, err = store.List(ctx, repository.LinkFilter{Tag: "sale", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	// Updates move the link between the filter indexes
+	link.OriginalURL = "https://b.com/new"
+	require.NoError(t, store.Update(ctx, link, 0))
+	page, err = store.List(ctx, repository.LinkFilter{Host: "a.com", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	require.NoError(t, store.Close())
+
+	// Files written before the filter indexes existed are indexed when opened
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("short_url_indexes"))
+	}))
+	require.NoError(t, db.Close())
+
+	store, err = storage.NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	page, err = store.List(ctx, repository.LinkFilter{Host: "b.com", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "reused", page[0].Code)
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"context"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShortUrl_RejectsUnsafeDestinations(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	for _, originalURL := range []string{
+		"javascript:alert(document.cookie)",
+		"JavaScript:alert(1)",
+		"data:text/html;base64,PHNjcmlwdD4=",
+		"not a url",
+		"example.com/page",
+		"https://",
+		"https:evil.example/login",
+		"HTTP:evil.example",
+		"ftp://files.example/report.pdf",
+		"https://accounts.example@evil.example/login",
+		"https://exa mple.com",
+		"   ",
+	} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: originalURL})
+		assert.ErrorIs(t, err, usecase.ErrInvalidOriginalURL, originalURL)
+	}
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+	script := "javascript:alert(1)"
+	_, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{OriginalUrl: &script})
+	assert.ErrorIs(t, err, usecase.ErrInvalidOriginalURL)
+}
+
+func TestCreateShortUrl_CanonicalizesDestinations(t *testing.T) {
+	store := storage.NewMemoryStore()
+	cfg := &config.Config{MaximumShortUrlCount: 100}
+	cfg.URL.AllowedSchemes = []string{"https", "http", "mailto"}
+	cfg.URL.SortQuery = true
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+
+	for raw, canonical := range map[string]string{
+		"HTTPS://Bücher.Example:443/Katalog?b=2&a=1": "https://xn--bcher-kva.example/Katalog?a=1&b=2",
+		"http://Example.com:80/":                     "http://example.com/",
+		"http://example.com:8080/x":                  "http://example.com:8080/x",
+		"https://[2001:DB8::1]:443/":                 "https://[2001:db8::1]/",
+		"mailto:team@example.com":                    "mailto:team@example.com",
+	} {
+		created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: raw})
+		require.NoError(t, err, raw)
+		link, err := uc.GetShortUrlByCode(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, canonical, link.OriginalUrl, raw)
+	}
+
+	// Spellings of the same destination share one code
+	first, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://shop.example/?utm_source=x&id=7"})
+	require.NoError(t, err)
+	second, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://SHOP.example:443/?id=7&utm_source=x"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, second.ID)
+}
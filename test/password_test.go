@@ -0,0 +1,170 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/infrastructure/ratelimit"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"shorter-rest-api/internal/interfaces/middleware"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortUrlUseCase_PasswordProtectedLinks(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/secret", Password: "abc"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidPassword)
+
+	open, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/secret"})
+	require.NoError(t, err)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/secret", Password: "hunter22"})
+	require.NoError(t, err)
+	// Protected links are never reused for other requests
+	assert.NotEqual(t, open.ID, created.ID)
+	again, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/secret"})
+	require.NoError(t, err)
+	assert.NotEqual(t, created.ID, again.ID)
+	assert.Equal(t, open.ID, again.ID)
+
+	link, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, link.Protected)
+
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrPasswordRequired)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{Password: "hunter2"})
+	assert.ErrorIs(t, err, usecase.ErrWrongPassword)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{AccessToken: "9999999999.00"})
+	assert.ErrorIs(t, err, usecase.ErrPasswordRequired)
+
+	result, err := uc.Redirect(ctx, created.ID, &dto.RedirectRequest{Password: "hunter22"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/secret", result.Location)
+	require.NotEmpty(t, result.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), result.AccessExpiresAt, time.Minute)
+
+	result, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{AccessToken: result.AccessToken})
+	require.NoError(t, err)
+	assert.Empty(t, result.AccessToken)
+
+	// Tokens only open the link they were issued for
+	other, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/other", Password: "hunter22"})
+	require.NoError(t, err)
+	token, err := uc.Redirect(ctx, created.ID, &dto.RedirectRequest{Password: "hunter22"})
+	require.NoError(t, err)
+	_, err = uc.Redirect(ctx, other.ID, &dto.RedirectRequest{AccessToken: token.AccessToken})
+	assert.ErrorIs(t, err, usecase.ErrPasswordRequired)
+
+	// Changing the password revokes the tokens, removing it opens the link
+	password := "correct horse"
+	_, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{Password: &password})
+	require.NoError(t, err)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{AccessToken: token.AccessToken})
+	assert.ErrorIs(t, err, usecase.ErrPasswordRequired)
+	password = ""
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{Password: &password})
+	require.NoError(t, err)
+	assert.False(t, link.Protected)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.NoError(t, err)
+}
+
+func TestShortUrlUseCase_ProtectedDestinationsStayWithOwner(t *testing.T) {
+	uc := newTestUseCase()
+	growth := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "growth"})
+	support := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "support"})
+	admin := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "admin", Admin: true})
+
+	created, _, err := uc.CreateShortUrl(growth, &dto.CreateRequest{OriginalUrl: "https://example.com/secret", Password: "hunter22"})
+	require.NoError(t, err)
+
+	_, err = uc.GetShortUrlByCode(support, created.ID)
+	assert.ErrorIs(t, err, usecase.ErrForbidden)
+	_, err = uc.PreviewShortUrl(support, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrForbidden)
+	_, err = uc.Redirect(support, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrPasswordRequired)
+
+	for _, caller := range []context.Context{growth, admin} {
+		link, err := uc.GetShortUrlByCode(caller, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/secret", link.OriginalUrl)
+	}
+}
+
+func TestRedirect_PasswordFormAndCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, nil)
+	_, _, err := uc.CreateShortUrl(context.Background(), &dto.CreateRequest{
+		OriginalUrl: "https://example.com/form", Alias: "vault", Password: "hunter22", RedirectStatus: http.StatusTemporaryRedirect,
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	limits := api.RouteLimits{
+		Password: middleware.RateLimitBy(ratelimit.NewMemoryLimiter(), "password", ratelimit.Limit{Requests: 3, Window: time.Minute}, api.PasswordGuessKey),
+	}
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, limits)
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	browser := func(method, password string) *http.Request {
+		var req *http.Request
+		if password == "" {
+			req = httptest.NewRequest(method, "/shortlinks/vault?ref=mail", nil)
+		} else {
+			req = httptest.NewRequest(method, "/shortlinks/vault?ref=mail", strings.NewReader(url.Values{"link_password": {password}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		return req
+	}
+
+	response := serve(browser(http.MethodGet, ""))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Contains(t, response.Body.String(), `name="link_password"`)
+	assert.NotContains(t, response.Body.String(), "Wrong password")
+
+	response = serve(browser(http.MethodPost, "wrong-one"))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Contains(t, response.Body.String(), "Wrong password")
+
+	// The form is not posted on to the destination
+	response = serve(browser(http.MethodPost, "hunter22"))
+	require.Equal(t, http.StatusSeeOther, response.Code)
+	assert.Equal(t, "https://example.com/form", response.Header().Get("Location"))
+	cookies := response.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "/shortlinks/vault", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+
+	req := browser(http.MethodGet, "")
+	req.AddCookie(cookies[0])
+	assert.Equal(t, http.StatusTemporaryRedirect, serve(req).Code)
+
+	// API clients give the password in a header and get JSON errors
+	req = httptest.NewRequest(http.MethodGet, "/shortlinks/vault", nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+	assert.Contains(t, serve(req).Body.String(), `"error"`)
+	req.Header.Set(api.PasswordHeader, "hunter22")
+	assert.Equal(t, http.StatusTemporaryRedirect, serve(req).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(req).Code, "guesses are limited per link")
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/policy"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blocklistEntry returns the blocklist line of a URL expression with a
+// prefix of length bytes
+func blocklistEntry(expression string, length int, threat string) string {
+	hash := sha256.Sum256([]byte(expression))
+	return strings.TrimSpace(hex.EncodeToString(hash[:length]) + " " + threat)
+}
+
+// writeBlocklist replaces the blocklist file at once, so that the watcher
+// never reads it half written
+func writeBlocklist(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	temp := path + ".tmp"
+	require.NoError(t, os.WriteFile(temp, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+	require.NoError(t, os.Rename(temp, path))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path,
+		"# threats",
+		blocklistEntry("evil.example/", 4, "malware"),
+		blocklistEntry("docs.example/phish/", 32, "social engineering"),
+		"",
+		blocklistEntry("bad.example/login.html", 8, ""),
+	)
+	engine, err := policy.New(policy.Options{
+		AllowDomains:  []string{"*.Example", "bücher.de"},
+		DenyDomains:   []string{"ads.example"},
+		BlocklistPath: path,
+	})
+	require.NoError(t, err)
+	defer engine.Close()
+
+	for destination, want := range map[string]string{
+		"https://docs.example/guide":                  "",
+		"https://xn--bcher-kva.de/":                   "",
+		"https://other.org/":                          policy.ReasonNotAllowed,
+		"https://ads.example/banner":                  policy.ReasonDenied,
+		"https://cdn.ads.example/banner":              policy.ReasonDenied,
+		"https://evil.example":                        "malware",
+		"https://a.b.evil.example/x/y?z=1":            "malware",
+		"https://docs.example/phish/login":            "social engineering",
+		"https://docs.example/phishing":               "",
+		"http://bad.example/login.html?next=/":        policy.DefaultThreat,
+		"http://bad.example/login.htm":                "",
+		"mailto:someone@other.org":                    "",
+		"https:evil.example/login":                    policy.ReasonNoHost,
+		"http:///evil.example":                        policy.ReasonNoHost,
+		"https://docs.example:8443/phish/a/b/c/d.php": "social engineering",
+	} {
+		assert.Equal(t, want, engine.Check(destination), destination)
+	}
+}
+
+func TestPolicy_RejectsBrokenBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "abc")
+	_, err := policy.New(policy.Options{BlocklistPath: path})
+	assert.Error(t, err)
+
+	_, err = policy.New(policy.Options{BlocklistPath: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
+
+func TestPolicy_ReloadsBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "# empty")
+	engine, err := policy.New(policy.Options{BlocklistPath: path, ReloadInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer engine.Close()
+	assert.Empty(t, engine.Check("https://evil.example/"))
+
+	writeBlocklist(t, path, blocklistEntry("evil.example/", 4, "malware"))
+	assert.Eventually(t, func() bool {
+		return engine.Check("https://evil.example/") == "malware"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// A broken file keeps the entries in use
+	writeBlocklist(t, path, "not hex")
+	assert.Error(t, engine.Reload())
+	assert.Equal(t, "malware", engine.Check("https://evil.example/"))
+
+	writeBlocklist(t, path, "# cleared")
+	require.NoError(t, engine.Reload())
+	assert.Empty(t, engine.Check("https://evil.example/"))
+}
+
+func TestCreateShortUrl_BlockedDestination(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, blocklistEntry("evil.example/", 4, "malware"))
+	engine, err := policy.New(policy.Options{DenyDomains: []string{"ads.example"}, BlocklistPath: path})
+	require.NoError(t, err)
+	defer engine.Close()
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, engine, nil)
+	ctx := context.Background()
+
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://EVIL.example/download"})
+	var blocked *usecase.BlockedDestinationError
+	require.True(t, errors.As(err, &blocked), err)
+	assert.ErrorIs(t, err, usecase.ErrDestinationBlocked)
+	assert.Equal(t, "malware", blocked.Reason)
+
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://ads.example/{1}"})
+	assert.ErrorIs(t, err, usecase.ErrDestinationBlocked)
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/page"})
+	require.NoError(t, err)
+	blockedURL := "https://evil.example/"
+	_, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{OriginalUrl: &blockedURL})
+	assert.ErrorIs(t, err, usecase.ErrDestinationBlocked)
+}
+
+func TestRedirect_BlockedDestinationServesInterstitial(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "# empty")
+	engine, err := policy.New(policy.Options{BlocklistPath: path})
+	require.NoError(t, err)
+	defer engine.Close()
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, engine, nil)
+	_, _, err = uc.CreateShortUrl(context.Background(), &dto.CreateRequest{OriginalUrl: "https://evil.example/<b>login</b>", Alias: "promo"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/shortlinks/promo", nil))
+		return recorder
+	}
+	require.Equal(t, http.StatusFound, serve().Code)
+
+	// Links created before their destination was listed are blocked on visit
+	writeBlocklist(t, path, blocklistEntry("evil.example/", 4, "phishing"))
+	require.NoError(t, engine.Reload())
+	response := serve()
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	assert.Empty(t, response.Header().Get("Location"))
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/html")
+	body := response.Body.String()
+	assert.Contains(t, body, "phishing")
+	assert.NotContains(t, body, "<b>")
+	assert.NotContains(t, body, "href")
+}
@@ -0,0 +1,323 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"shorter-rest-api/internal/interfaces/middleware"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func quotaRepositories(t *testing.T) map[string]repository.QuotaRepository {
+	boltStore, err := storage.NewBoltStore(filepath.Join(t.TempDir(), "quota.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boltStore.Close() })
+
+	return map[string]repository.QuotaRepository{
+		"redis":  &cache.RedisClient{Conn: newTestRedisPool(t)},
+		"memory": storage.NewMemoryStore(),
+		"file":   boltStore,
+	}
+}
+
+func TestLinkRepository_CountsActiveLinksPerOwner(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			past := now.Add(-time.Hour)
+			links := []*entity.ShortURL{
+				{Code: "a1", OriginalURL: "https://a.example/1", CreatedAt: now, OwnerID: "a"},
+				{Code: "a2", OriginalURL: "https://a.example/2", CreatedAt: now, OwnerID: "a"},
+				{Code: "a3", OriginalURL: "https://a.example/3", CreatedAt: now, OwnerID: "a", ExpiresAt: &past},
+				{Code: "b1", OriginalURL: "https://b.example/1", CreatedAt: now, OwnerID: "b"},
+			}
+			for _, link := range links {
+				require.NoError(t, repo.Create(ctx, link, time.Hour, repository.LinkLimits{}))
+			}
+
+			count, err := repo.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 3, count)
+			count, err = repo.CountByOwner(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+
+			// Soft deleting and moving links updates the counters
+			links[0].DeletedAt = &now
+			require.NoError(t, repo.Update(ctx, links[0], time.Hour))
+			links[3].OwnerID = "a"
+			require.NoError(t, repo.Update(ctx, links[3], time.Hour))
+			count, err = repo.CountByOwner(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+			count, err = repo.CountByOwner(ctx, "b")
+			require.NoError(t, err)
+			assert.Equal(t, 0, count)
+
+			require.NoError(t, repo.Delete(ctx, "a2"))
+			count, err = repo.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			// Links stop counting once they expire
+			soon := time.Now().Add(50 * time.Millisecond)
+			require.NoError(t, repo.Create(ctx, &entity.ShortURL{Code: "a4", OriginalURL: "https://a.example/4", CreatedAt: now, OwnerID: "a", ExpiresAt: &soon}, time.Hour, repository.LinkLimits{}))
+			count, err = repo.CountByOwner(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+			time.Sleep(100 * time.Millisecond)
+			count, err = repo.CountByOwner(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			// Restored links count again
+			links[0].DeletedAt = nil
+			require.NoError(t, repo.Update(ctx, links[0], time.Hour))
+			count, err = repo.CountByOwner(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+		})
+	}
+}
+
+func TestLinkRepository_ConcurrentCreatesStayWithinLimits(t *testing.T) {
+	for name, repo := range linkRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			create := func(prefix, ownerID string, limits repository.LinkLimits) (created, refused int32) {
+				var wg sync.WaitGroup
+				for i := range 20 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						link := &entity.ShortURL{Code: fmt.Sprintf("%s%d", prefix, i), OriginalURL: "https://example.com", CreatedAt: time.Now(), OwnerID: ownerID}
+						err := repo.Create(ctx, link, time.Hour, limits)
+						switch {
+						case err == nil:
+							atomic.AddInt32(&created, 1)
+						case errors.Is(err, repository.ErrOwnerLinkLimit), errors.Is(err, repository.ErrTotalLinkLimit):
+							atomic.AddInt32(&refused, 1)
+						default:
+							assert.NoError(t, err)
+						}
+					}()
+				}
+				wg.Wait()
+				return created, refused
+			}
+
+			created, refused := create("a", "a", repository.LinkLimits{Owner: 5})
+			assert.Equal(t, int32(5), created)
+			assert.Equal(t, int32(15), refused)
+			created, _ = create("b", "b", repository.LinkLimits{Total: 8, Owner: 5})
+			assert.Equal(t, int32(3), created)
+
+			err := repo.Create(ctx, &entity.ShortURL{Code: "c", OriginalURL: "https://example.com", OwnerID: "c"}, time.Hour, repository.LinkLimits{Total: 8})
+			assert.ErrorIs(t, err, repository.ErrTotalLinkLimit)
+			count, err := repo.Count(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 8, count)
+		})
+	}
+}
+
+func TestBoltStore_CountsLinksOfFilesWithoutCounters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.db")
+	store, err := storage.NewBoltStore(path)
+	require.NoError(t, err)
+	for _, code := range []string{"a1", "a2"} {
+		require.NoError(t, store.Create(ctx, &entity.ShortURL{Code: code, OriginalURL: "https://a.example/" + code, CreatedAt: time.Now(), OwnerID: "a"}, 0, repository.LinkLimits{}))
+	}
+	require.NoError(t, store.Close())
+
+	// Files written before the counters existed have no usage buckets
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte("short_url_usage")); err != nil {
+			return err
+		}
+		return tx.DeleteBucket([]byte("short_url_usage_counts"))
+	}))
+	require.NoError(t, db.Close())
+
+	store, err = storage.NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	count, err := store.CountByOwner(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestQuotaRepository_DailyCreates(t *testing.T) {
+	for name, repo := range quotaRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			day := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+
+			for want := 1; want <= 2; want++ {
+				used, ok, err := repo.ReserveDailyCreate(ctx, "a", day, 2)
+				require.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, want, used)
+			}
+			used, ok, err := repo.ReserveDailyCreate(ctx, "a", day, 2)
+			require.NoError(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, 2, used)
+
+			require.NoError(t, repo.ReleaseDailyCreate(ctx, "a", day))
+			_, ok, err = repo.ReserveDailyCreate(ctx, "a", day, 2)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			// Counters are per owner and per UTC day
+			used, ok, err = repo.ReserveDailyCreate(ctx, "b", day, 2)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, 1, used)
+			used, ok, err = repo.ReserveDailyCreate(ctx, "a", day.Add(24*time.Hour), 2)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, 1, used)
+		})
+	}
+}
+
+func TestShortUrlUseCase_QuotasPerAPIKey(t *testing.T) {
+	store := storage.NewMemoryStore()
+	cfg := &config.Config{MaximumShortUrlCount: 100, DeletedLinkRetention: 3600}
+	cfg.Quota.MaxLinks = 2
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+
+	limited := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "growth"})
+	create := func(ctx context.Context, url string) (*dto.CreateResponse, error) {
+		response, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: url})
+		return response, err
+	}
+
+	first, err := create(limited, "https://example.com/1")
+	require.NoError(t, err)
+	require.NotNil(t, first.Quota)
+	assert.Equal(t, 1, first.Quota.LinksRemaining)
+	_, err = create(limited, "https://example.com/2")
+	require.NoError(t, err)
+
+	_, err = create(limited, "https://example.com/3")
+	var quotaErr *usecase.QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.ErrorIs(t, err, usecase.ErrQuotaExceeded)
+	assert.Equal(t, usecase.QuotaLinks, quotaErr.Quota)
+
+	// Reusing an existing link does not take a slot
+	_, err = create(limited, "https://example.com/1")
+	require.NoError(t, err)
+
+	// Deleting frees a slot, restoring needs one
+	require.NoError(t, uc.DeleteShortUrl(limited, first.ID, false))
+	_, err = create(limited, "https://example.com/3")
+	require.NoError(t, err)
+	_, err = uc.RestoreShortUrl(limited, first.ID)
+	assert.ErrorIs(t, err, usecase.ErrQuotaExceeded)
+
+	// Keys may override the default, admins have no quotas
+	unlimited := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "support", MaxLinks: -1})
+	admin := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "admin", Admin: true, MaxLinks: 1})
+	for i, ctx := range []context.Context{unlimited, unlimited, unlimited, admin, admin} {
+		_, err := create(ctx, "https://example.org/"+string(rune('a'+i)))
+		require.NoError(t, err)
+	}
+}
+
+func TestShortUrlUseCase_ConcurrentCreatesStayWithinQuota(t *testing.T) {
+	store := storage.NewMemoryStore()
+	cfg := &config.Config{MaximumShortUrlCount: 100}
+	cfg.Quota.MaxLinks = 3
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := usecase.WithCaller(context.Background(), &entity.APIKey{ID: "growth"})
+
+	var created, refused atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: fmt.Sprintf("https://example.com/%d", i)})
+			if err == nil {
+				created.Add(1)
+			} else if assert.ErrorIs(t, err, usecase.ErrQuotaExceeded) {
+				refused.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 3, created.Load())
+	assert.EqualValues(t, 17, refused.Load())
+}
+
+func TestCreateShortUrl_DailyQuotaResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	keys := newTestAPIKeyUseCase(store)
+	cfg := &config.Config{MaximumShortUrlCount: 100}
+	cfg.Quota.MaxDailyCreates = 1
+	links := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+
+	admin, err := keys.Authenticate(context.Background(), "bootstrap-secret")
+	require.NoError(t, err)
+	issued, err := keys.IssueAPIKey(usecase.WithCaller(context.Background(), admin), &dto.IssueAPIKeyRequest{Name: "growth", MaxLinks: 5})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(links, usecase.NewStatsUseCase(store, store, nil), config.DefaultRedirectPrefix).RegisterRoutes(router, middleware.APIKeyAuth(keys, true), api.RouteLimits{})
+	create := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/shortlinks", strings.NewReader(`{"original_url":"`+url+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", issued.Secret)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	response := create("https://example.com/1")
+	require.Equal(t, http.StatusCreated, response.Code, response.Body.String())
+	assert.Equal(t, "5", response.Header().Get("X-Quota-Links-Limit"))
+	assert.Equal(t, "4", response.Header().Get("X-Quota-Links-Remaining"))
+	assert.Equal(t, "1", response.Header().Get("X-Quota-Daily-Creates-Limit"))
+	assert.Equal(t, "0", response.Header().Get("X-Quota-Daily-Creates-Remaining"))
+	assert.NotEmpty(t, response.Header().Get("X-Quota-Reset"))
+
+	response = create("https://example.com/2")
+	require.Equal(t, http.StatusTooManyRequests, response.Code, response.Body.String())
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, usecase.QuotaDailyCreates, body["quota"])
+	assert.EqualValues(t, 1, body["limit"])
+	assert.EqualValues(t, 0, body["remaining"])
+	assert.NotEmpty(t, body["reset_at"])
+}
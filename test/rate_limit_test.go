@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/infrastructure/ratelimit"
+	"shorter-rest-api/internal/interfaces/middleware"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimiters(t *testing.T) map[string]ratelimit.Limiter {
+	return map[string]ratelimit.Limiter{
+		"redis":  ratelimit.NewRedisLimiter(newTestRedisPool(t)),
+		"memory": ratelimit.NewMemoryLimiter(),
+	}
+}
+
+// failingLimiter stands for a shared limiter whose store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestLimiter_AllowsBurstThenRejects(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 3, Window: time.Minute}
+	for name, limiter := range rateLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for want := 2; want >= 0; want-- {
+				result, err := limiter.Allow(ctx, "client-a", limit)
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, want, result.Remaining)
+			}
+
+			result, err := limiter.Allow(ctx, "client-a", limit)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 0, result.Remaining)
+			// One request frees up every window / requests
+			assert.InDelta(t, 20*time.Second, result.RetryAfter, float64(time.Second))
+			assert.InDelta(t, time.Minute, result.ResetAfter, float64(time.Second))
+
+			result, err = limiter.Allow(ctx, "client-b", limit)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+		})
+	}
+}
+
+func TestLimiter_FallsBackWhenUnavailable(t *testing.T) {
+	limiter := ratelimit.WithFallback(failingLimiter{}, ratelimit.NewMemoryLimiter())
+	limit := ratelimit.Limit{Requests: 1, Window: time.Minute}
+
+	result, err := limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limit := ratelimit.Limit{Requests: 2, Window: time.Minute}
+	assert.Nil(t, middleware.RateLimit(ratelimit.NewMemoryLimiter(), "redirect", ratelimit.Limit{}))
+
+	router := gin.New()
+	router.GET("/limited", middleware.RateLimit(ratelimit.NewMemoryLimiter(), "redirect", limit), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = ip + ":1234"
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	response := request("10.0.0.1")
+	require.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, "2", response.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", response.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", response.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", response.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusNoContent, request("10.0.0.1").Code)
+	response = request("10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "30", response.Header().Get("Retry-After"))
+	assert.Equal(t, "0", response.Header().Get("RateLimit-Remaining"))
+
+	// Clients are limited separately
+	assert.Equal(t, http.StatusNoContent, request("10.0.0.2").Code)
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirect_ServedAtRoot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	cfg := newDomainTestConfig()
+	cfg.Server.RedirectPrefix = ""
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	created, _, err := uc.CreateShortUrl(context.Background(), &dto.CreateRequest{OriginalUrl: "https://example.com/sale", Alias: "sale"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://sho.rt/sale", created.ShortUrl)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), cfg.Server.RedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	router.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	serve := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	for _, target := range []string{"/sale", config.DefaultRedirectPrefix + "/sale"} {
+		response := serve(target)
+		require.Equal(t, http.StatusFound, response.Code, target)
+		assert.Equal(t, "https://example.com/sale", response.Header().Get("Location"), target)
+	}
+
+	// System routes keep precedence over codes
+	assert.Equal(t, "pong", serve("/ping").Body.String())
+	assert.Equal(t, http.StatusOK, serve("/api/shortlinks/sale").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/unknown").Code)
+
+	_, _, err = uc.CreateShortUrl(context.Background(), &dto.CreateRequest{OriginalUrl: "https://example.com/ping", Alias: "ping"})
+	assert.ErrorIs(t, err, usecase.ErrAliasReserved)
+}
+
+func TestRedirect_StatusPerLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	cfg := newDomainTestConfig()
+	cfg.Server.RedirectStatus = http.StatusMovedPermanently
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+
+	seo, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/landing"})
+	require.NoError(t, err)
+	hook, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/landing", RedirectStatus: http.StatusPermanentRedirect})
+	require.NoError(t, err)
+	assert.NotEqual(t, seo.ID, hook.ID, "links redirecting otherwise are not reused")
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/ok", RedirectStatus: http.StatusOK})
+	assert.ErrorIs(t, err, usecase.ErrInvalidRedirectStatus)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(method, code string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, "/shortlinks/"+code, nil))
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusMovedPermanently, serve(http.MethodGet, seo.ID))
+	assert.Equal(t, http.StatusPermanentRedirect, serve(http.MethodGet, hook.ID))
+	assert.Equal(t, http.StatusPermanentRedirect, serve(http.MethodPost, hook.ID))
+
+	temporary := http.StatusTemporaryRedirect
+	updated, err := uc.UpdateShortUrl(ctx, hook.ID, &dto.UpdateRequest{RedirectStatus: &temporary})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, updated.RedirectStatus)
+	assert.Equal(t, http.StatusTemporaryRedirect, serve(http.MethodPut, hook.ID))
+
+	serverDefault := 0
+	updated, err = uc.UpdateShortUrl(ctx, hook.ID, &dto.UpdateRequest{RedirectStatus: &serverDefault})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMovedPermanently, updated.RedirectStatus)
+}
+
+func TestRedirect_Passthrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	cfg := newDomainTestConfig()
+	cfg.Server.RedirectPrefix = ""
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+	create := func(request *dto.CreateRequest) string {
+		created, _, err := uc.CreateShortUrl(ctx, request)
+		require.NoError(t, err)
+		return created.ID
+	}
+	plain := create(&dto.CreateRequest{OriginalUrl: "https://example.com/docs?ref=short"})
+	incoming := create(&dto.CreateRequest{OriginalUrl: "https://example.com/docs?ref=short", ForwardQuery: true, ForwardPath: true})
+	kept := create(&dto.CreateRequest{OriginalUrl: "https://example.com/docs?ref=short", ForwardQuery: true, QueryConflict: "destination"})
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", QueryConflict: "both"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidQueryConflict)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), cfg.Server.RedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	cases := map[string]string{
+		"/" + plain + "?utm_source=x":                 "https://example.com/docs?ref=short",
+		"/" + incoming + "?utm_source=x&ref=campaign": "https://example.com/docs?ref=campaign&utm_source=x",
+		"/" + incoming + "/guides/setup/":             "https://example.com/docs/guides/setup/?ref=short",
+		"/" + incoming + "/../admin?utm_source=x":     "https://example.com/docs/admin?ref=short&utm_source=x",
+		"/shortlinks/" + incoming + "/api":            "https://example.com/docs/api?ref=short",
+		"/" + kept + "?utm_source=x&ref=campaign":     "https://example.com/docs?ref=short&utm_source=x",
+		"/" + kept: "https://example.com/docs?ref=short",
+	}
+	for target, want := range cases {
+		response := serve(target)
+		require.Equal(t, http.StatusFound, response.Code, target)
+		assert.Equal(t, want, response.Header().Get("Location"), target)
+	}
+
+	// Links not forwarding paths do not answer them
+	assert.Equal(t, http.StatusNotFound, serve("/"+plain+"/extra").Code)
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/analytics"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	iPhoneUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36"
+	desktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+)
+
+// countryTable locates client addresses from a fixed table
+type countryTable map[string]string
+
+func (t countryTable) Country(ip string) string {
+	if country, ok := t[ip]; ok {
+		return country
+	}
+	return analytics.UnknownCountry
+}
+
+func TestShortUrlUseCase_RoutingRules(t *testing.T) {
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, countryTable{"203.0.113.7": "DE"})
+	ctx := context.Background()
+	now := time.Now().UTC()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	open, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/app", ForwardQuery: true})
+	require.NoError(t, err)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{
+		OriginalUrl:  "https://example.com/app",
+		ForwardQuery: true,
+		RoutingRules: []dto.RoutingRule{
+			{OS: []string{"ios"}, Destination: "https://apps.apple.com/app/id123"},
+			{OS: []string{"Android"}, Devices: []string{"Mobile", "tablet"}, Destination: "https://play.google.com/store/apps/details?id=app"},
+			{Languages: []string{"FR"}, Destination: "https://example.com/fr/app"},
+			{Countries: []string{"de"}, Destination: "https://example.de/app"},
+			{
+				TimeFrom: now.Add(time.Hour).Format("15:04"), TimeTo: now.Add(2 * time.Hour).Format("15:04"),
+				Destination: "https://example.com/later",
+			},
+			{
+				TimeFrom: now.In(tokyo).Add(-time.Hour).Format("15:04"), TimeTo: now.In(tokyo).Add(time.Hour).Format("15:04"), Timezone: "Asia/Tokyo",
+				Destination: "https://example.com/now",
+			},
+		},
+	})
+	require.NoError(t, err)
+	// Routed links are never reused for other requests
+	assert.NotEqual(t, open.ID, created.ID)
+	again, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/app", ForwardQuery: true})
+	require.NoError(t, err)
+	assert.Equal(t, open.ID, again.ID)
+
+	link, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, link.RoutingRules, 6)
+	assert.Equal(t, []string{"iOS"}, link.RoutingRules[0].OS)
+	assert.Equal(t, []string{"mobile", "tablet"}, link.RoutingRules[1].Devices)
+	assert.Equal(t, []string{"DE"}, link.RoutingRules[3].Countries)
+
+	for name, test := range map[string]struct {
+		request dto.RedirectRequest
+		want    string
+	}{
+		"ios":              {dto.RedirectRequest{UserAgent: iPhoneUserAgent, Query: "ref=qr"}, "https://apps.apple.com/app/id123?ref=qr"},
+		"android":          {dto.RedirectRequest{UserAgent: androidUserAgent}, "https://play.google.com/store/apps/details?id=app"},
+		"preferred french": {dto.RedirectRequest{UserAgent: desktopUserAgent, AcceptLanguage: "en;q=0.4, fr-CA;q=0.9"}, "https://example.com/fr/app"},
+		"other language":   {dto.RedirectRequest{UserAgent: desktopUserAgent, AcceptLanguage: "en-US, fr;q=0.8"}, "https://example.com/now"},
+		"located country":  {dto.RedirectRequest{UserAgent: desktopUserAgent, ClientIP: "203.0.113.7"}, "https://example.de/app"},
+		"given country":    {dto.RedirectRequest{UserAgent: desktopUserAgent, Country: "de"}, "https://example.de/app"},
+		"time of day":      {dto.RedirectRequest{UserAgent: desktopUserAgent, ClientIP: "198.51.100.1"}, "https://example.com/now"},
+	} {
+		result, err := uc.Redirect(ctx, created.ID, &test.request)
+		require.NoError(t, err, name)
+		assert.Equal(t, test.want, result.Location, name)
+		assert.True(t, result.Routed, name)
+	}
+
+	// Removing the rules sends every visit to the original URL
+	rules := []dto.RoutingRule{}
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{RoutingRules: &rules})
+	require.NoError(t, err)
+	assert.Empty(t, link.RoutingRules)
+	result, err := uc.Redirect(ctx, created.ID, &dto.RedirectRequest{UserAgent: iPhoneUserAgent})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/app", result.Location)
+	assert.False(t, result.Routed)
+}
+
+func TestCreateShortUrl_RejectsInvalidRoutingRules(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+	for name, rule := range map[string]dto.RoutingRule{
+		"no condition":     {Destination: "https://example.com/x"},
+		"unknown os":       {OS: []string{"BeOS"}, Destination: "https://example.com/x"},
+		"unknown device":   {Devices: []string{"watch"}, Destination: "https://example.com/x"},
+		"bad language":     {Languages: []string{"fr_FR"}, Destination: "https://example.com/x"},
+		"bad country":      {Countries: []string{"DEU"}, Destination: "https://example.com/x"},
+		"half window":      {TimeFrom: "09:00", Destination: "https://example.com/x"},
+		"empty window":     {TimeFrom: "09:00", TimeTo: "09:00", Destination: "https://example.com/x"},
+		"unknown timezone": {TimeFrom: "09:00", TimeTo: "17:00", Timezone: "Mars/Olympus", Destination: "https://example.com/x"},
+		"template":         {OS: []string{"iOS"}, Destination: "https://example.com/{1}"},
+		"lonely timezone":  {Timezone: "UTC", OS: []string{"iOS"}, Destination: "https://example.com/x"},
+		"bad time format":  {TimeFrom: "9am", TimeTo: "17:00", Destination: "https://example.com/x"},
+	} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/app", RoutingRules: []dto.RoutingRule{rule}})
+		assert.ErrorIs(t, err, usecase.ErrInvalidRoutingRule, name)
+	}
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{
+		OriginalUrl:  "https://example.com/app",
+		RoutingRules: []dto.RoutingRule{{OS: []string{"iOS"}, Destination: "ftp://example.com/app"}},
+	})
+	assert.ErrorIs(t, err, usecase.ErrInvalidOriginalURL)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{
+		OriginalUrl:  "https://jira.example/browse/{1}",
+		RoutingRules: []dto.RoutingRule{{OS: []string{"iOS"}, Destination: "https://apps.apple.com/app/id123"}},
+	})
+	assert.ErrorIs(t, err, usecase.ErrInvalidRoutingRule)
+}
+
+func TestRedirect_RoutesByUserAgentWithoutCaching(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(newDomainTestConfig(), store, store, store, nil, nil)
+	_, _, err := uc.CreateShortUrl(context.Background(), &dto.CreateRequest{
+		OriginalUrl: "https://example.com/app", Alias: "get-app", RedirectStatus: http.StatusMovedPermanently,
+		RoutingRules: []dto.RoutingRule{
+			{OS: []string{"iOS"}, Destination: "https://apps.apple.com/app/id123"},
+			{OS: []string{"Android"}, Destination: "https://play.google.com/store/apps/details?id=app"},
+		},
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for userAgent, want := range map[string]string{
+		iPhoneUserAgent:  "https://apps.apple.com/app/id123",
+		androidUserAgent: "https://play.google.com/store/apps/details?id=app",
+		desktopUserAgent: "https://example.com/app",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/shortlinks/get-app", nil)
+		req.Header.Set("User-Agent", userAgent)
+		response := serve(req)
+		require.Equal(t, http.StatusMovedPermanently, response.Code)
+		assert.Equal(t, want, response.Header().Get("Location"))
+		assert.Equal(t, "no-store", response.Header().Get("Cache-Control"))
+	}
+
+	// Previews tell where a given visitor would go
+	req := httptest.NewRequest(http.MethodGet, "/api/shortlinks/get-app?user_agent="+url.QueryEscape(androidUserAgent), nil)
+	response := serve(req)
+	require.Equal(t, http.StatusOK, response.Code)
+	var preview dto.GetShortUrlResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &preview))
+	assert.Equal(t, "https://play.google.com/store/apps/details?id=app", preview.Preview)
+	assert.Len(t, preview.RoutingRules, 2)
+}
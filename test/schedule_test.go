@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortUrlUseCase_ScheduledLinks(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+	now := time.Now()
+	tomorrow, yesterday := now.Add(24*time.Hour), now.Add(-24*time.Hour)
+	start, end, soon := tomorrow.Format(time.RFC3339), yesterday.Format(time.RFC3339), "soon"
+
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &start, NotAfter: &end})
+	assert.ErrorIs(t, err, usecase.ErrInvalidSchedule)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &soon})
+	assert.ErrorIs(t, err, usecase.ErrInvalidSchedule)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &start, FallbackURL: "https://example.com/{1}"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidSchedule)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &start, FallbackURL: "ftp://example.com/"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidOriginalURL)
+
+	open, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale"})
+	require.NoError(t, err)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &start})
+	require.NoError(t, err)
+	// Scheduled links are never reused for other requests, empty bounds
+	// ask for no schedule
+	assert.NotEqual(t, open.ID, created.ID)
+	unset := ""
+	again, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotBefore: &unset, NotAfter: &unset})
+	require.NoError(t, err)
+	assert.Equal(t, open.ID, again.ID)
+
+	// Creates take dates like updates, a not_after date covering its whole day
+	day := "2020-01-01"
+	ended, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", NotAfter: &day})
+	require.NoError(t, err)
+	link, err := uc.GetShortUrlByCode(ctx, ended.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "2020-01-01 23:59:59", link.NotAfter)
+	assert.Equal(t, entity.ScheduleEnded, link.ScheduleState)
+
+	link, err = uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ScheduleScheduled, link.ScheduleState)
+	assert.NotEmpty(t, link.NotBefore)
+	openLink, err := uc.GetShortUrlByCode(ctx, open.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ScheduleActive, openLink.ScheduleState)
+
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrLinkNotYetActive)
+
+	// A fallback URL takes visits until the link goes live
+	fallback := "https://example.com/teaser"
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{FallbackURL: &fallback})
+	require.NoError(t, err)
+	assert.Equal(t, fallback, link.FallbackURL)
+	result, err := uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	require.NoError(t, err)
+	assert.True(t, result.Fallback)
+	assert.Equal(t, fallback, result.Location)
+	assert.Equal(t, http.StatusFound, result.Status)
+
+	// Moving the window makes the link live, then ends it
+	notBefore, notAfter := yesterday.Format(time.RFC3339), tomorrow.Format(time.RFC3339)
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{NotBefore: &notBefore, NotAfter: &notAfter})
+	require.NoError(t, err)
+	assert.Equal(t, entity.ScheduleActive, link.ScheduleState)
+	result, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	require.NoError(t, err)
+	assert.False(t, result.Fallback)
+	assert.Equal(t, "https://example.com/sale", result.Location)
+
+	notAfter, fallback = now.Add(-time.Hour).Format(time.RFC3339), ""
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{NotAfter: &notAfter, FallbackURL: &fallback})
+	require.NoError(t, err)
+	assert.Equal(t, entity.ScheduleEnded, link.ScheduleState)
+	_, err = uc.Redirect(ctx, created.ID, &dto.RedirectRequest{})
+	assert.ErrorIs(t, err, usecase.ErrLinkEnded)
+
+	// Dates cover their whole day, empty values remove the bounds
+	notBefore, notAfter = "", "2020-01-01"
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{NotBefore: &notBefore, NotAfter: &notAfter})
+	require.NoError(t, err)
+	assert.Empty(t, link.NotBefore)
+	assert.Equal(t, "2020-01-01 23:59:59", link.NotAfter)
+	notAfter = ""
+	link, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{NotAfter: &notAfter})
+	require.NoError(t, err)
+	assert.Empty(t, link.NotAfter)
+	assert.Equal(t, entity.ScheduleActive, link.ScheduleState)
+	_, err = uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{NotAfter: &soon})
+	assert.ErrorIs(t, err, usecase.ErrInvalidSchedule)
+}
+
+func TestRedirect_ScheduledLinkComingSoonAndFallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := newDomainTestConfig()
+	cfg.Schedule.ComingSoonMessage = "the spring sale opens soon"
+	store := storage.NewMemoryStore()
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+	launch := time.Now().Add(time.Hour).Format(time.RFC3339)
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", Alias: "spring", NotBefore: &launch})
+	require.NoError(t, err)
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{
+		OriginalUrl: "https://example.com/sale", Alias: "summer", NotBefore: &launch, FallbackURL: "https://example.com/teaser",
+	})
+	require.NoError(t, err)
+
+	clicks := &clickLog{}
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, clicks), config.DefaultRedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(code, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/shortlinks/"+code, nil)
+		req.Header.Set("Accept", accept)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	response := serve("spring", "text/html")
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, response.Body.String(), "the spring sale opens soon")
+	retryAfter, err := strconv.Atoi(response.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 3600, retryAfter, 5)
+
+	response = serve("spring", "application/json")
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Contains(t, response.Body.String(), `"not_before"`)
+
+	response = serve("summer", "text/html")
+	assert.Equal(t, http.StatusFound, response.Code)
+	assert.Equal(t, "https://example.com/teaser", response.Header().Get("Location"))
+	assert.Empty(t, clicks.events, "visits outside the window are not clicks")
+}
@@ -0,0 +1,271 @@
+package test
+
+import (
+	"context"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/config"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUseCase() usecase.ShortUrlUseCase {
+	cfg := &config.Config{MaximumShortUrlCount: 100, Expiration: 3600, ExpiredLinkRetention: 3600, DeletedLinkRetention: 3600}
+	cfg.Server.Port = "8080"
+	cfg.Alias.Charset = "abcdefghijklmnopqrstuvwxyz0123456789-"
+	cfg.Alias.MinLength = 3
+	cfg.Alias.MaxLength = 20
+	cfg.Alias.ReservedWords = []string{"api", "swagger"}
+	store := storage.NewMemoryStore()
+	return usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+}
+
+func TestCreateShortUrl_ReturnsExistingLinkForDuplicate(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	first, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://Example.com/page"})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	second, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: " https://example.COM/page "})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first, second)
+}
+
+func TestCreateShortUrl_ForceNewMintsFreshCode(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	first, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+
+	second, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", ForceNew: true})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// Neither a fresh code nor an alias takes the destination from the first link
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", Alias: "example"})
+	require.NoError(t, err)
+	third, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, third.ID)
+}
+
+func TestCreateShortUrl_IdempotencyKeyReplaysFirstResult(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	req := &dto.CreateRequest{OriginalUrl: "https://example.com", ForceNew: true, IdempotencyKey: "retry-1"}
+	first, created, err := uc.CreateShortUrl(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	second, created, err := uc.CreateShortUrl(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, second.ID)
+
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://other.com", IdempotencyKey: "retry-1"})
+	assert.ErrorIs(t, err, usecase.ErrIdempotencyKeyReused)
+}
+
+// idempotencyLog records the lifetimes an idempotency store is asked for
+type idempotencyLog struct {
+	*storage.MemoryStore
+	claimTTL, completeTTL time.Duration
+	released              int
+}
+
+func (l *idempotencyLog) ClaimIdempotencyKey(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	l.claimTTL = ttl
+	return l.MemoryStore.ClaimIdempotencyKey(ctx, key, ttl)
+}
+
+func (l *idempotencyLog) CompleteIdempotencyKey(ctx context.Context, key, code string, ttl time.Duration) error {
+	l.completeTTL = ttl
+	return l.MemoryStore.CompleteIdempotencyKey(ctx, key, code, ttl)
+}
+
+func (l *idempotencyLog) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	l.released++
+	return l.MemoryStore.ReleaseIdempotencyKey(ctx, key)
+}
+
+func TestCreateShortUrl_IdempotencyKeyClaimedOnlyWhileInFlight(t *testing.T) {
+	cfg := &config.Config{MaximumShortUrlCount: 100, Expiration: 3600}
+	store := storage.NewMemoryStore()
+	keys := &idempotencyLog{MemoryStore: store}
+	uc := usecase.NewShortUrlUseCase(cfg, store, keys, store, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// A failed request frees its key for the retry, even once the caller left
+	cancel()
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", RedirectStatus: 200, IdempotencyKey: "retry-1"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidRedirectStatus)
+	assert.Equal(t, 1, keys.released)
+
+	created, isNew, err := uc.CreateShortUrl(context.Background(), &dto.CreateRequest{OriginalUrl: "https://example.com", IdempotencyKey: "retry-1"})
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, 1, keys.released)
+
+	// The claim is short lived, the recorded code is kept for the full TTL
+	assert.Positive(t, keys.claimTTL)
+	assert.Less(t, keys.claimTTL, keys.completeTTL)
+	assert.Equal(t, 24*time.Hour, keys.completeTTL)
+
+	replayed, isNew, err := uc.CreateShortUrl(context.Background(), &dto.CreateRequest{OriginalUrl: "https://example.com", IdempotencyKey: "retry-1"})
+	require.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, created.ID, replayed.ID)
+}
+
+func TestCreateShortUrl_Alias(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	result, created, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/sale", Alias: "spring-sale"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "spring-sale", result.ID)
+
+	_, _, err = uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com/other", Alias: "spring-sale"})
+	assert.ErrorIs(t, err, usecase.ErrAliasTaken)
+}
+
+func TestCreateShortUrl_InvalidAlias(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	cases := map[string]error{
+		"ab":          usecase.ErrInvalidAlias,
+		"Spring_Sale": usecase.ErrInvalidAlias,
+		"API":         usecase.ErrAliasReserved,
+		"metrics":     usecase.ErrAliasReserved,
+		"shortlinks":  usecase.ErrAliasReserved,
+	}
+	for alias, expected := range cases {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", Alias: alias})
+		assert.ErrorIs(t, err, expected, alias)
+	}
+}
+
+func TestCreateShortUrl_NeverExpires(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	never := int64(0)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", TTLSeconds: &never})
+	require.NoError(t, err)
+
+	result, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, result.ExpiresAt)
+}
+
+func TestGetShortUrlByCode_ExpiredThenExtended(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(20 * time.Millisecond)
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = uc.GetShortUrlByCode(ctx, created.ID)
+	assert.ErrorIs(t, err, usecase.ErrLinkExpired)
+
+	ttl := int64(60)
+	_, err = uc.UpdateExpiration(ctx, created.ID, &dto.UpdateExpirationRequest{TTLSeconds: &ttl})
+	require.NoError(t, err)
+
+	result, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.ExpiresAt)
+}
+
+func TestUpdateExpiration_RequiresExactlyOneField(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+
+	_, err = uc.UpdateExpiration(ctx, created.ID, &dto.UpdateExpirationRequest{})
+	assert.ErrorIs(t, err, usecase.ErrInvalidExpiration)
+}
+
+func TestDeleteShortUrl_SoftDeleteAndRestore(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://example.com"})
+	require.NoError(t, err)
+
+	require.NoError(t, uc.DeleteShortUrl(ctx, created.ID, false))
+	_, err = uc.GetShortUrlByCode(ctx, created.ID)
+	assert.ErrorIs(t, err, usecase.ErrLinkDeleted)
+
+	restored, err := uc.RestoreShortUrl(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", restored.OriginalUrl)
+
+	_, err = uc.RestoreShortUrl(ctx, created.ID)
+	assert.ErrorIs(t, err, usecase.ErrLinkNotDeleted)
+
+	require.NoError(t, uc.DeleteShortUrl(ctx, created.ID, true))
+	_, err = uc.GetShortUrlByCode(ctx, created.ID)
+	assert.ErrorIs(t, err, usecase.ErrLinkNotFound)
+}
+
+func TestUpdateShortUrl_ChangesDestinationAndMetadata(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://old.com"})
+	require.NoError(t, err)
+
+	destination := "https://new.com"
+	tags := []string{"Campaign", "campaign", " spring "}
+	updated, err := uc.UpdateShortUrl(ctx, created.ID, &dto.UpdateRequest{OriginalUrl: &destination, Tags: &tags})
+	require.NoError(t, err)
+	assert.Equal(t, "https://new.com", updated.OriginalUrl)
+	assert.Equal(t, []string{"campaign", "spring"}, updated.Tags)
+
+	// The old destination no longer resolves to this link
+	again, created2, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://old.com"})
+	require.NoError(t, err)
+	assert.True(t, created2)
+	assert.NotEqual(t, created.ID, again.ID)
+}
+
+func TestListShortUrls_CursorPagination(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	for _, destination := range []string{"https://a.com/1", "https://a.com/2", "https://b.com/3"} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: destination})
+		require.NoError(t, err)
+	}
+
+	first, err := uc.ListShortUrls(ctx, &dto.ListRequest{Limit: 2, Sort: "created_at"})
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := uc.ListShortUrls(ctx, &dto.ListRequest{Limit: 2, Sort: "created_at", Cursor: first.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Empty(t, second.NextCursor)
+
+	_, err = uc.ListShortUrls(ctx, &dto.ListRequest{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidListQuery)
+}
@@ -0,0 +1,205 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/domain/entity"
+	"shorter-rest-api/internal/domain/repository"
+	"shorter-rest-api/internal/infrastructure/analytics"
+	"shorter-rest-api/internal/infrastructure/cache"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsRepositories(t *testing.T) map[string]repository.StatsRepository {
+	boltStore, err := storage.NewBoltStore(filepath.Join(t.TempDir(), "stats.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { boltStore.Close() })
+
+	return map[string]repository.StatsRepository{
+		"redis":  &cache.RedisClient{Conn: newTestRedisPool(t), MinuteRetention: time.Hour, StatsRetention: 24 * time.Hour},
+		"memory": storage.NewMemoryStore(),
+		"file":   boltStore,
+	}
+}
+
+func TestStatsRepository_BucketsAndBreakdowns(t *testing.T) {
+	for name, repo := range statsRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Date(2025, 7, 1, 23, 58, 0, 0, time.UTC)
+			clicks := []struct {
+				at       time.Duration
+				referrer string
+				country  string
+			}{
+				{0, "direct", "VN"},
+				{30 * time.Second, "t.co", "VN"},
+				{time.Minute, "t.co", "US"},
+				{3 * time.Minute, "news.ycombinator.com", "US"}, // next day
+			}
+			events := []*entity.ClickEvent{{Code: "other", OccurredAt: base, ReferrerHost: "direct", Browser: "Firefox", Country: "VN"}}
+			for _, click := range clicks {
+				events = append(events, &entity.ClickEvent{
+					Code: "abc", OccurredAt: base.Add(click.at),
+					ReferrerHost: click.referrer, Browser: "Chrome", Country: click.country,
+				})
+			}
+			require.NoError(t, repo.RecordClicks(ctx, events))
+
+			stats, err := repo.GetClickStats(ctx, repository.StatsQuery{
+				Code: "abc", From: base, To: base.Add(3 * time.Minute), Granularity: entity.GranularityMinute,
+			})
+			require.NoError(t, err)
+			require.Len(t, stats.Series, 4)
+			assert.Equal(t, []int64{2, 1, 0, 1}, []int64{stats.Series[0].Clicks, stats.Series[1].Clicks, stats.Series[2].Clicks, stats.Series[3].Clicks})
+			assert.Equal(t, base, stats.Series[0].Start)
+			assert.Equal(t, map[string]int64{"direct": 1, "t.co": 2, "news.ycombinator.com": 1}, stats.Breakdowns[entity.DimensionReferrer])
+			assert.Equal(t, map[string]int64{"Chrome": 4}, stats.Breakdowns[entity.DimensionBrowser])
+
+			day := entity.GranularityDay.BucketStart(base)
+			stats, err = repo.GetClickStats(ctx, repository.StatsQuery{
+				Code: "abc", From: day, To: day, Granularity: entity.GranularityDay,
+			})
+			require.NoError(t, err)
+			require.Len(t, stats.Series, 1)
+			assert.Equal(t, int64(3), stats.Series[0].Clicks)
+			assert.Equal(t, map[string]int64{"VN": 2, "US": 1}, stats.Breakdowns[entity.DimensionCountry])
+		})
+	}
+}
+
+func TestStatsRepository_DeletedLinkDropsStats(t *testing.T) {
+	for name, repo := range statsRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			links := repo.(repository.LinkRepository)
+			now := time.Now().UTC()
+			day := entity.GranularityDay.BucketStart(now)
+			query := repository.StatsQuery{Code: "gone", From: day, To: day, Granularity: entity.GranularityDay}
+			require.NoError(t, links.Create(ctx, &entity.ShortURL{Code: "gone", OriginalURL: "https://example.com", CreatedAt: now}, 0, repository.LinkLimits{}))
+			require.NoError(t, repo.RecordClicks(ctx, []*entity.ClickEvent{
+				{Code: "gone", OccurredAt: now, ReferrerHost: "t.co", Browser: "Chrome", Country: "VN", VisitorID: "visitor"},
+			}))
+			if redisClient, ok := repo.(*cache.RedisClient); ok {
+				conn := redisClient.Conn.Get()
+				ttl, err := redis.Int(conn.Do("TTL", "clicks:gone:day"))
+				conn.Close()
+				require.NoError(t, err)
+				assert.Positive(t, ttl, "day buckets expire with the stats retention")
+			}
+
+			// A link created again under the code does not inherit the stats
+			require.NoError(t, links.Delete(ctx, "gone"))
+			require.NoError(t, links.Create(ctx, &entity.ShortURL{Code: "gone", OriginalURL: "https://example.org", CreatedAt: now}, 0, repository.LinkLimits{}))
+			stats, err := repo.GetClickStats(ctx, query)
+			require.NoError(t, err)
+			assert.Zero(t, stats.Series[0].Clicks)
+			assert.Empty(t, stats.Breakdowns[entity.DimensionReferrer])
+			assert.Zero(t, stats.UniqueVisitors)
+		})
+	}
+}
+
+func TestStatsRepository_UniqueVisitors(t *testing.T) {
+	for name, repo := range statsRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			day := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+			// 2000 visitors on the first day, each clicking twice, and 1000
+			// of them coming back on the second day with 500 new ones
+			var events []*entity.ClickEvent
+			for repeat := 0; repeat < 2; repeat++ {
+				for i := 0; i < 2000; i++ {
+					events = append(events, &entity.ClickEvent{Code: "abc", OccurredAt: day, VisitorID: fmt.Sprintf("visitor-%d", i)})
+				}
+			}
+			for i := 1000; i < 2500; i++ {
+				events = append(events, &entity.ClickEvent{Code: "abc", OccurredAt: day.Add(24 * time.Hour), VisitorID: fmt.Sprintf("visitor-%d", i)})
+			}
+			for len(events) > 0 {
+				batch := events[:min(len(events), 500)]
+				require.NoError(t, repo.RecordClicks(ctx, batch))
+				events = events[len(batch):]
+			}
+
+			stats, err := repo.GetClickStats(ctx, repository.StatsQuery{
+				Code: "abc", From: day, To: day.Add(48 * time.Hour), Granularity: entity.GranularityDay,
+			})
+			require.NoError(t, err)
+			require.Len(t, stats.DailyVisitors, 3)
+			assert.InEpsilon(t, 2000, stats.DailyVisitors[0].Visitors, 0.05)
+			assert.InEpsilon(t, 1500, stats.DailyVisitors[1].Visitors, 0.05)
+			assert.Zero(t, stats.DailyVisitors[2].Visitors)
+			// miniredis adds up multi-key PFCOUNT instead of counting the union
+			if name != "redis" {
+				assert.InEpsilon(t, 2500, stats.UniqueVisitors, 0.05)
+			}
+			assert.Equal(t, int64(5500), stats.Series[0].Clicks+stats.Series[1].Clicks)
+		})
+	}
+}
+
+func TestParseUserAgent(t *testing.T) {
+	iphone := analytics.ParseUserAgent("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1")
+	assert.Equal(t, analytics.UserAgent{Family: "Safari", OS: "iOS", Device: analytics.DeviceMobile}, iphone)
+
+	edge := analytics.ParseUserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0")
+	assert.Equal(t, analytics.UserAgent{Family: "Edge", OS: "Windows", Device: analytics.DeviceDesktop}, edge)
+
+	assert.Equal(t, analytics.DeviceBot, analytics.ParseUserAgent("Googlebot/2.1 (+http://www.google.com/bot.html)").Device)
+	assert.Equal(t, "Other", analytics.ParseUserAgent("").Family)
+}
+
+func TestClickStats_RecordedAsynchronously(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	geo, err := analytics.NewGeoLocator("")
+	require.NoError(t, err)
+	recorder := analytics.NewRecorder(store, geo, analytics.RecorderOptions{QueueSize: 10, BatchSize: 2, FlushInterval: time.Hour, Salt: "salt"})
+	stats := usecase.NewStatsUseCase(store, store, recorder)
+	require.NoError(t, store.Create(ctx, &entity.ShortURL{Code: "abc", OriginalURL: "https://example.com", CreatedAt: time.Now()}, 0, repository.LinkLimits{}))
+
+	stats.TrackClick("abc", &dto.ClickRequest{ClientIP: "203.0.113.7", UserAgent: "curl/8.0", Referrer: "https://www.Google.com/search?q=x"})
+	stats.TrackClick("abc", &dto.ClickRequest{ClientIP: "203.0.113.8"})
+	stats.TrackClick("abc", &dto.ClickRequest{ClientIP: "203.0.113.8"})
+	require.NoError(t, recorder.Close(ctx))
+
+	result, err := stats.GetClickStats(ctx, "abc", &dto.StatsRequest{Granularity: "hour"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.TotalClicks)
+	assert.Equal(t, int64(2), result.UniqueVisitors)
+	assert.Len(t, result.Series, 25)
+	assert.Equal(t, map[string]int64{"google.com": 1, "direct": 2}, result.Referrers)
+	assert.Equal(t, map[string]int64{"Bot": 1, "Other": 2}, result.Browsers)
+	assert.Equal(t, map[string]int64{"unknown": 3}, result.Countries)
+}
+
+func TestClickStats_RejectsInvalidQuery(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	stats := usecase.NewStatsUseCase(store, store, nil)
+	require.NoError(t, store.Create(ctx, &entity.ShortURL{Code: "abc", OriginalURL: "https://example.com"}, 0, repository.LinkLimits{}))
+
+	_, err := stats.GetClickStats(ctx, "abc", &dto.StatsRequest{Granularity: "week"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidStatsQuery)
+	_, err = stats.GetClickStats(ctx, "abc", &dto.StatsRequest{Granularity: "minute", From: "2025-07-01", To: "2025-07-03"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidStatsQuery)
+	_, err = stats.GetClickStats(ctx, "abc", &dto.StatsRequest{From: "2025-07-03", To: "2025-07-01"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidStatsQuery)
+	_, err = stats.GetClickStats(ctx, "missing", &dto.StatsRequest{})
+	assert.ErrorIs(t, err, usecase.ErrLinkNotFound)
+
+	result, err := stats.GetClickStats(ctx, "abc", &dto.StatsRequest{From: "2025-07-01", To: "2025-07-03"})
+	require.NoError(t, err)
+	assert.Len(t, result.Series, 3)
+	assert.Equal(t, "2025-07-03 23:59:59", result.To)
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"shorter-rest-api/internal/application/usecase"
+	"shorter-rest-api/internal/domain/dto"
+	"shorter-rest-api/internal/infrastructure/storage"
+	"shorter-rest-api/internal/interfaces/api"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShortUrl_ValidatesTemplates(t *testing.T) {
+	uc := newTestUseCase()
+	ctx := context.Background()
+
+	for _, template := range []string{
+		"https://jira.example/browse/{0}",
+		"https://jira.example/browse/{name}",
+		"https://jira.example/browse/{1",
+		"https://{1}.example/browse",
+		"https://jira.example/{query}",
+	} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: template})
+		assert.ErrorIs(t, err, usecase.ErrInvalidTemplate, template)
+	}
+	_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "https://jira.example/browse/{1}", ForwardPath: true})
+	assert.ErrorIs(t, err, usecase.ErrInvalidTemplate)
+
+	created, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: "HTTPS://Jira.Example/browse/{1}?q={query}", Alias: "jira"})
+	require.NoError(t, err)
+	link, err := uc.GetShortUrlByCode(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://jira.example/browse/{1}?q={query}", link.OriginalUrl)
+	assert.True(t, link.Template)
+}
+
+func TestRedirect_ExpandsTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	cfg := newDomainTestConfig()
+	uc := usecase.NewShortUrlUseCase(cfg, store, store, store, nil, nil)
+	ctx := context.Background()
+	for alias, template := range map[string]string{
+		"jira":   "https://jira.example/browse/{1}",
+		"git":    "https://github.example/{1}/{2}/tree/main/{*}",
+		"search": "https://search.example/?q={*}&{query}",
+	} {
+		_, _, err := uc.CreateShortUrl(ctx, &dto.CreateRequest{OriginalUrl: template, Alias: alias})
+		require.NoError(t, err)
+	}
+
+	router := gin.New()
+	router.ContextWithFallback = true
+	api.NewShortUrlController(uc, usecase.NewStatsUseCase(store, store, &clickLog{}), cfg.Server.RedirectPrefix).RegisterRoutes(router, nil, api.RouteLimits{})
+	serve := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	for target, want := range map[string]string{
+		"/shortlinks/jira/PROJ-123":              "https://jira.example/browse/PROJ-123",
+		"/shortlinks/git/acme/api/docs/intro.md": "https://github.example/acme/api/tree/main/docs/intro.md",
+		"/shortlinks/git/acme/api":               "https://github.example/acme/api/tree/main/",
+		"/shortlinks/search/go%20links?lang=en":  "https://search.example/?q=go+links&lang=en",
+		"/shortlinks/jira/a%3Fb":                 "https://jira.example/browse/a%3Fb",
+	} {
+		response := serve(target)
+		require.Equal(t, http.StatusFound, response.Code, target)
+		assert.Equal(t, want, response.Header().Get("Location"), target)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve("/shortlinks/jira").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/shortlinks/jira/PROJ-1/extra").Code)
+
+	response := serve("/api/shortlinks/git?path=acme/api/README.md")
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	var preview dto.GetShortUrlResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &preview))
+	assert.True(t, preview.Template)
+	assert.Equal(t, "https://github.example/acme/api/tree/main/README.md", preview.Preview)
+	assert.Equal(t, http.StatusBadRequest, serve("/api/shortlinks/git?path=acme").Code)
+}